@@ -0,0 +1,74 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(amCmd)
+	flagset := amCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.String("strategy", "", `Resolve conflicts with HEAD using the given strategy, one of "ours", "theirs". Am fails on conflicts by default.`)
+}
+
+var amCmd = &cobra.Command{
+	Use:   "am",
+	Short: "Apply patch files created by format-patch on top of HEAD.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) == 1 {
+			return errors.New("at least one patch file must be specified")
+		}
+
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		rawStrategy, _ := cmd.Flags().GetString("strategy")
+		strategy := libocitree.RebaseConflictStrategyFromString(rawStrategy)
+		if strategy == libocitree.UnknownRebaseConflictStrategy {
+			return fmt.Errorf("unknown conflict strategy %q", rawStrategy)
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("failed to retrieve repository %q: %v", repoName, err)
+			os.Exit(1)
+		}
+
+		err = repo.ApplyPatches(libocitree.ApplyPatchesOptions{
+			ConflictStrategy: strategy,
+			ReportWriter:     os.Stderr,
+		}, args[1:]...)
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("HEAD is now at %v\n", shortID(repo.ID()))
+
+		return nil
+	},
+}
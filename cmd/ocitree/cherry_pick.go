@@ -0,0 +1,91 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(cherryPickCmd)
+	flagset := cherryPickCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.String("strategy", "", `Resolve conflicts with HEAD using the given strategy, one of "ours", "theirs". Cherry-pick fails on conflicts by default.`)
+}
+
+var cherryPickCmd = &cobra.Command{
+	Use:   "cherry-pick",
+	Short: "Apply commits of any repository on top of HEAD.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) == 1 {
+			return errors.New("at least one commit reference must be specified")
+		}
+
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		relRefs := make([]reference.Relative, len(args)-1)
+		for i, arg := range args[1:] {
+			relRefs[i], err = reference.RelativeFromString(arg)
+			if err != nil {
+				return fmt.Errorf("reference %q invalid: %v", arg, err)
+			}
+		}
+
+		rawStrategy, _ := cmd.Flags().GetString("strategy")
+		strategy := libocitree.RebaseConflictStrategyFromString(rawStrategy)
+		if strategy == libocitree.UnknownRebaseConflictStrategy {
+			return fmt.Errorf("unknown conflict strategy %q", rawStrategy)
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("failed to retrieve repository %q: %v", repoName, err)
+			os.Exit(1)
+		}
+
+		refs := make([]reference.Reference, len(relRefs))
+		for i, relRef := range relRefs {
+			refs[i], err = manager.ResolveRelativeReference(relRef)
+			if err != nil {
+				logrus.Errorf("failed to resolve relative reference %q: %v", relRef, err)
+				os.Exit(1)
+			}
+		}
+
+		err = repo.CherryPick(libocitree.CherryPickOptions{
+			ConflictStrategy: strategy,
+			ReportWriter:     os.Stderr,
+		}, refs...)
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("HEAD is now at %v\n", shortID(repo.ID()))
+
+		return nil
+	},
+}
@@ -0,0 +1,75 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+	flagset := commitCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupCommitOptionsFlags(flagset)
+}
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Commit changes made in a mounted repository.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		flags := cmd.Flags()
+		message, _ := flags.GetString("message")
+
+		err = repo.CommitWorkingContainer(libocitree.WorkingContainerCommitOptions{
+			Message:      message,
+			ReportWriter: os.Stderr,
+		})
+		if errors.Is(err, libocitree.ErrNothingToCommit) {
+			fmt.Println(err)
+			return nil
+		}
+		if err != nil {
+			logrus.Errorf("failed to commit working container: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("HEAD is now at %v\n", repo.ID()[:16])
+		fmt.Printf("Working container was reset, run \"%v mount %v\" to mount it again.\n", os.Args[0], repoName)
+
+		return nil
+	},
+}
@@ -36,3 +36,12 @@ var commitOpts = commitOptions{}
 func setupCommitOptionsFlags(flagset *pflag.FlagSet) {
 	flagset.StringVarP(&commitOpts.message, "message", "m", "", "commit message")
 }
+
+// shortID returns the first 16 characters of the given commit ID.
+func shortID(id string) string {
+	if len(id) > 16 {
+		return id[:16]
+	}
+
+	return id
+}
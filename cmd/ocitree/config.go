@@ -0,0 +1,129 @@
+package ocitree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	flagset := configCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupCommitOptionsFlags(flagset)
+	flagset.StringArray("env", nil, "Set an environment variable using the KEY=VALUE format.")
+	flagset.String("entrypoint", "", `Set entrypoint, either as a JSON array or as a command run with "/bin/sh -c".`)
+	flagset.String("cmd", "", `Set default command, either as a JSON array or as a command run with "/bin/sh -c".`)
+	flagset.String("workdir", "", "Set working directory.")
+	flagset.String("user", "", "Set user, and optionally group, commands are run as.")
+	flagset.StringArray("label", nil, "Set a label using the KEY=VALUE format.")
+	flagset.StringArray("expose", nil, "Expose a port using the port[/protocol] format.")
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Commit image configuration changes on HEAD.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		message, _ := flags.GetString("message")
+		env, _ := flags.GetStringArray("env")
+		workdir, _ := flags.GetString("workdir")
+		user, _ := flags.GetString("user")
+		labels, _ := flags.GetStringArray("label")
+		expose, _ := flags.GetStringArray("expose")
+		entrypoint, err := commandFlag(flags, "entrypoint")
+		if err != nil {
+			return err
+		}
+		command, err := commandFlag(flags, "cmd")
+		if err != nil {
+			return err
+		}
+
+		changes := libocitree.ConfigChanges{
+			Env:        env,
+			Entrypoint: entrypoint,
+			Cmd:        command,
+			WorkDir:    workdir,
+			User:       user,
+			Labels:     labels,
+			Expose:     expose,
+		}
+		if changes.IsEmpty() {
+			return libocitree.ErrConfigNoChanges
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		err = repo.Config(libocitree.ConfigOptions{
+			Changes:      changes,
+			Message:      message,
+			ReportWriter: os.Stderr,
+		})
+		if err != nil {
+			logrus.Errorf("failed to commit configuration changes: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("HEAD is now at %v\n", shortID(repo.ID()))
+
+		return nil
+	},
+}
+
+// commandFlag parses the command flag with the given name. Command is either
+// a JSON array or a string run using "/bin/sh -c". Nil is returned if flag
+// isn't set.
+func commandFlag(flags *pflag.FlagSet, name string) ([]string, error) {
+	if !flags.Changed(name) {
+		return nil, nil
+	}
+
+	rawCommand, _ := flags.GetString(name)
+	if !strings.HasPrefix(strings.TrimSpace(rawCommand), "[") {
+		return []string{"/bin/sh", "-c", rawCommand}, nil
+	}
+
+	command := []string{}
+	err := json.Unmarshal([]byte(rawCommand), &command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v flag: %w", name, err)
+	}
+
+	return command, nil
+}
@@ -0,0 +1,189 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	flagset := diffCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupDiffOptionsFlags(flagset)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show changes between two references, second reference defaults to HEAD.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository reference must be specified")
+		}
+		if len(args) > 2 {
+			return errors.New("too many arguments specified")
+		}
+
+		relRefs := make([]reference.Relative, len(args))
+		for i, arg := range args {
+			relRef, err := reference.RelativeFromString(arg)
+			if err != nil {
+				return err
+			}
+			relRefs[i] = relRef
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		refs := make([]reference.Reference, len(relRefs))
+		for i, relRef := range relRefs {
+			refs[i], err = manager.ResolveRelativeReference(relRef)
+			if err != nil {
+				logrus.Errorf("failed to resolve relative reference %q: %v", args[i], err)
+				os.Exit(1)
+			}
+		}
+
+		repo, err := manager.Repository(refs[0].Name())
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		from := refs[0]
+		var to reference.Reference = repo.HeadRef()
+		if len(refs) == 2 {
+			to = refs[1]
+		}
+
+		changes, err := repo.Diff(from, to, libocitree.DiffOptions{
+			Content: diffOpts.content(),
+		})
+		if err != nil {
+			logrus.Errorf("failed to compute diff between %q and %q: %v", from, to, err)
+			os.Exit(1)
+		}
+
+		printFileChanges(os.Stdout, changes)
+
+		return nil
+	},
+}
+
+type diffOptions struct {
+	nameStatus bool
+	stat       bool
+}
+
+// content returns true if file content is needed to print changes.
+func (do diffOptions) content() bool {
+	return !do.nameStatus
+}
+
+var diffOpts = diffOptions{}
+
+func setupDiffOptionsFlags(flagset *pflag.FlagSet) {
+	flagset.BoolVar(&diffOpts.nameStatus, "name-status", false, "Show only names and status of changed files.")
+	flagset.BoolVar(&diffOpts.stat, "stat", false, "Show number of lines added and deleted per file.")
+}
+
+// printFileChanges prints the given changes using format selected by diff
+// options flags.
+func printFileChanges(w io.Writer, changes []libocitree.FileChange) {
+	switch {
+	case diffOpts.nameStatus:
+		printNameStatus(w, changes)
+	case diffOpts.stat:
+		printStat(w, changes)
+	default:
+		printPatch(w, changes)
+	}
+}
+
+func printNameStatus(w io.Writer, changes []libocitree.FileChange) {
+	for _, change := range changes {
+		fmt.Fprintf(w, "%v\t%v\n", change.Kind.Short(), change.Path)
+	}
+}
+
+func printStat(w io.Writer, changes []libocitree.FileChange) {
+	pathWidth := 0
+	for _, change := range changes {
+		if len(change.Path) > pathWidth {
+			pathWidth = len(change.Path)
+		}
+	}
+
+	totalAdded, totalDeleted := 0, 0
+	for _, change := range changes {
+		if !isRegularFile(change.Old) && !isRegularFile(change.New) {
+			fmt.Fprintf(w, " %-*v | %v\n", pathWidth, change.Path, change.Kind)
+			continue
+		}
+
+		added, deleted, ok := change.LineStats()
+		if !ok {
+			fmt.Fprintf(w, " %-*v | Bin %v -> %v bytes\n", pathWidth, change.Path, change.Old.Size, change.New.Size)
+			continue
+		}
+
+		totalAdded += added
+		totalDeleted += deleted
+		fmt.Fprintf(w, " %-*v | %v %v%v\n", pathWidth, change.Path, added+deleted,
+			strings.Repeat("+", added), strings.Repeat("-", deleted))
+	}
+
+	fmt.Fprintf(w, " %v file(s) changed, %v insertion(s)(+), %v deletion(s)(-)\n",
+		len(changes), totalAdded, totalDeleted)
+}
+
+func printPatch(w io.Writer, changes []libocitree.FileChange) {
+	for _, change := range changes {
+		fmt.Fprintf(w, "diff a%v b%v\n", change.Path, change.Path)
+
+		switch change.Kind {
+		case libocitree.AddedFileChange:
+			fmt.Fprintf(w, "new file mode %v\n", change.New.Mode)
+		case libocitree.DeletedFileChange:
+			fmt.Fprintf(w, "deleted file mode %v\n", change.Old.Mode)
+		case libocitree.ModifiedFileChange:
+			if change.Old.Mode != change.New.Mode {
+				fmt.Fprintf(w, "old mode %v\n", change.Old.Mode)
+				fmt.Fprintf(w, "new mode %v\n", change.New.Mode)
+			}
+		}
+
+		// Only regular files have content
+		if !isRegularFile(change.Old) && !isRegularFile(change.New) {
+			continue
+		}
+
+		if diff, ok := change.UnifiedDiff(); ok {
+			fmt.Fprint(w, diff)
+		} else {
+			fmt.Fprintf(w, "Binary files a%v and b%v differ\n", change.Path, change.Path)
+		}
+	}
+}
+
+func isRegularFile(version libocitree.FileVersion) bool {
+	return !version.Deleted && version.Mode.IsRegular()
+}
@@ -0,0 +1,79 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	flagset := exportCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.String("format", "rootfs-tar", `Export format, one of "rootfs-tar", "oci", "docker-archive".`)
+	flagset.StringP("output", "o", "", "Path of the exported archive or directory.")
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a repository reference as a rootfs tar, an OCI layout or a docker archive.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository reference must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+
+		flags := cmd.Flags()
+		rawFormat, _ := flags.GetString("format")
+		format := libocitree.ExportFormatFromString(rawFormat)
+		if format == libocitree.UnknownExportFormat {
+			return fmt.Errorf("unknown export format %q", rawFormat)
+		}
+		output, _ := flags.GetString("output")
+		if output == "" {
+			return errors.New("an output path must be specified")
+		}
+
+		relRef, err := reference.RelativeFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		ref, err := manager.ResolveRelativeReference(relRef)
+		if err != nil {
+			logrus.Errorf("failed to resolve relative reference: %v", err)
+			os.Exit(1)
+		}
+
+		err = manager.Export(ref, libocitree.ExportOptions{
+			Format:       format,
+			Output:       output,
+			ReportWriter: os.Stderr,
+		})
+		if err != nil {
+			logrus.Errorf("failed to export %q: %v", relRef, err)
+			os.Exit(1)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,92 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(flattenCmd)
+	flagset := flattenCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupCommitOptionsFlags(flagset)
+	flagset.String("mode", "commits", `Flatten mode, "commits" flattens ocitree commits on top of the base image, "image" flattens the whole image into a single layer.`)
+	flagset.StringP("tag", "t", "", "Tag flattened image instead of moving HEAD to it.")
+}
+
+var flattenCmd = &cobra.Command{
+	Use:   "flatten",
+	Short: "Flatten commits of HEAD into a single commit.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		rawMode, _ := flags.GetString("mode")
+		mode := libocitree.FlattenModeFromString(rawMode)
+		if mode == libocitree.UnknownFlattenMode {
+			return fmt.Errorf("unknown flatten mode %q", rawMode)
+		}
+
+		var tag reference.Tag
+		if rawTag, _ := flags.GetString("tag"); rawTag != "" {
+			tag, err = reference.RemoteTagFromString(rawTag)
+			if err != nil {
+				return fmt.Errorf("tag %q invalid: %v", rawTag, err)
+			}
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		message, _ := flags.GetString("message")
+
+		id, err := repo.Flatten(libocitree.FlattenOptions{
+			Mode:         mode,
+			Tag:          tag,
+			Message:      message,
+			ReportWriter: os.Stderr,
+		})
+		if err != nil {
+			logrus.Errorf("failed to flatten repository: %v", err)
+			os.Exit(1)
+		}
+
+		if tag != nil {
+			fmt.Printf("Tagged flattened image %v as %v\n", shortID(id), tag)
+		} else {
+			fmt.Printf("HEAD is now at %v\n", shortID(id))
+		}
+
+		return nil
+	},
+}
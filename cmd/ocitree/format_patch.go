@@ -0,0 +1,98 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(formatPatchCmd)
+	flagset := formatPatchCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.StringP("output-directory", "o", ".", "Directory where patch files are written.")
+}
+
+var formatPatchCmd = &cobra.Command{
+	Use:   "format-patch",
+	Short: "Write commits of a since..until range, or since a reference up to HEAD, as patch files.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) == 1 {
+			return errors.New("a commit range must be specified")
+		}
+		if len(args) > 2 {
+			return errors.New("too many arguments specified")
+		}
+
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		rawSince, rawUntil, isRange := strings.Cut(args[1], "..")
+		if !isRange {
+			rawUntil = reference.LocalFromName(repoName).String()
+		}
+
+		since, err := reference.RelativeFromString(rawSince)
+		if err != nil {
+			return fmt.Errorf("reference %q invalid: %v", rawSince, err)
+		}
+		until, err := reference.RelativeFromString(rawUntil)
+		if err != nil {
+			return fmt.Errorf("reference %q invalid: %v", rawUntil, err)
+		}
+
+		outputDir, _ := cmd.Flags().GetString("output-directory")
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("failed to retrieve repository %q: %v", repoName, err)
+			os.Exit(1)
+		}
+
+		sinceRef, err := manager.ResolveRelativeReference(since)
+		if err != nil {
+			logrus.Errorf("failed to resolve relative reference %q: %v", since, err)
+			os.Exit(1)
+		}
+		untilRef, err := manager.ResolveRelativeReference(until)
+		if err != nil {
+			logrus.Errorf("failed to resolve relative reference %q: %v", until, err)
+			os.Exit(1)
+		}
+
+		paths, err := repo.FormatPatches(sinceRef, untilRef, outputDir)
+		if err != nil {
+			logrus.Errorf("failed to format patches: %v", err)
+			os.Exit(1)
+		}
+
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+
+		return nil
+	},
+}
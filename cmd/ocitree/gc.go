@@ -0,0 +1,74 @@
+package ocitree
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	flagset := gcCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.Bool("dry-run", false, "Show images that would be removed without removing them.")
+	flagset.Duration("grace-period", time.Hour, "Keep unreachable images younger than the given duration.")
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove images unreachable from HEAD, tags and reflog of repositories.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := make([]reference.Name, len(args))
+		for i, arg := range args {
+			var err error
+			names[i], err = reference.NameFromString(arg)
+			if err != nil {
+				return err
+			}
+		}
+
+		flags := cmd.Flags()
+		dryRun, _ := flags.GetBool("dry-run")
+		gracePeriod, _ := flags.GetDuration("grace-period")
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		report, err := manager.GarbageCollect(libocitree.GarbageCollectOptions{
+			Repositories: names,
+			GracePeriod:  gracePeriod,
+			DryRun:       dryRun,
+		})
+		if err != nil {
+			logrus.Errorf("failed to garbage collect: %v", err)
+			os.Exit(1)
+		}
+
+		action, reclaimed := "Removed", "Reclaimed"
+		if dryRun {
+			action, reclaimed = "Would remove", "Would reclaim"
+		}
+
+		for _, img := range report.Images {
+			fmt.Printf("%v %v %v\n", action, shortID(img.ID), img.Names)
+		}
+		fmt.Printf("%v %v\n", reclaimed, units.BytesSize(float64(report.ReclaimedSize)))
+
+		return nil
+	},
+}
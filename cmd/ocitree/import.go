@@ -0,0 +1,79 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	flagset := importCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupCommitOptionsFlags(flagset)
+	flagset.String("dest", "/", "Directory where archive is applied.")
+	flagset.Bool("replace", false, "Remove content of destination directory before applying archive.")
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Commit a tar archive, possibly compressed, as a layer on HEAD.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) == 1 {
+			return errors.New("an archive must be specified")
+		}
+		if len(args) > 2 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		dest, _ := flags.GetString("dest")
+		replace, _ := flags.GetBool("replace")
+		message, _ := flags.GetString("message")
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		err = repo.Import(args[1], libocitree.ImportOptions{
+			Dest:         dest,
+			Replace:      replace,
+			Message:      message,
+			ReportWriter: os.Stderr,
+		})
+		if err != nil {
+			logrus.Errorf("failed to import %q: %v", args[1], err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("HEAD is now at %v\n", shortID(repo.ID()))
+
+		return nil
+	},
+}
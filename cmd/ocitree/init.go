@@ -0,0 +1,79 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	flagset := initCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupCommitOptionsFlags(flagset)
+	flagset.String("from-dir", "", "Import rootfs of the first commit from the given directory.")
+	flagset.String("from-tar", "", "Import rootfs of the first commit from the given tar archive.")
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a new local repository from scratch or from a rootfs.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		fromDir, _ := flags.GetString("from-dir")
+		fromTar, _ := flags.GetString("from-tar")
+		if fromDir != "" && fromTar != "" {
+			return errors.New("--from-dir and --from-tar are mutually exclusive")
+		}
+		message, _ := flags.GetString("message")
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		err = manager.Init(repoName, libocitree.InitOptions{
+			FromDir:      fromDir,
+			FromTar:      fromTar,
+			Message:      message,
+			ReportWriter: os.Stderr,
+		})
+		if err != nil {
+			logrus.Errorf("failed to initialize repository %q: %v", repoName, err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Initialized repository %v at %v\n", repoName, shortID(repo.ID()))
+
+		return nil
+	},
+}
@@ -1,9 +1,13 @@
 package ocitree
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/docker/go-units"
@@ -17,6 +21,74 @@ func init() {
 	rootCmd.AddCommand(logCmd)
 	flagset := logCmd.PersistentFlags()
 	setupStoreOptionsFlags(flagset)
+	flagset.Bool("oneline", false, "Show each commit on a single line.")
+	flagset.String("format", "", "Show commits using the given Go template, see \"ocitree log --json\" output for available fields.")
+	flagset.Bool("json", false, "Show commits as a JSON array.")
+	flagset.IntP("max-count", "n", 0, "Limit the number of commits to show.")
+	flagset.String("since", "", `Show commits more recent than the given date (e.g. "2.weeks.ago", "2022-11-02").`)
+	flagset.String("until", "", `Show commits older than the given date (e.g. "yesterday", "2022-11-02 15:04:05").`)
+	flagset.StringArray("operation", nil, `Show only commits created by the given operation (e.g. "exec", "add").`)
+	flagset.String("grep", "", "Show only commits whose message matches the given regular expression.")
+	flagset.Bool("ocitree-only", false, "Hide commits not created by ocitree, such as base image history.")
+	flagset.Bool("stat", false, "Show number of lines added and deleted per file changed by each commit.")
+}
+
+// logEntry holds the fields of a commit available to log templates and JSON
+// output.
+type logEntry struct {
+	ID        string        `json:"id"`
+	ShortID   string        `json:"shortId"`
+	Date      *time.Time    `json:"date"`
+	Size      int64         `json:"size"`
+	Tags      []string      `json:"tags"`
+	Operation string        `json:"operation"`
+	CreatedBy string        `json:"createdBy"`
+	Message   string        `json:"message"`
+	Subject   string        `json:"subject"`
+	Stat      []logFileStat `json:"stat,omitempty"`
+}
+
+// logFileStat holds the stat of a file changed by a commit.
+type logFileStat struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// Added and Deleted are nil for binary and non regular files.
+	Added   *int `json:"added"`
+	Deleted *int `json:"deleted"`
+}
+
+func newLogFileStats(changes []libocitree.FileChange) []logFileStat {
+	stats := make([]logFileStat, len(changes))
+	for i, change := range changes {
+		stats[i] = logFileStat{
+			Path:    change.Path,
+			Kind:    change.Kind.String(),
+			Added:   nil,
+			Deleted: nil,
+		}
+
+		if added, deleted, ok := change.LineStats(); ok {
+			stats[i].Added = &added
+			stats[i].Deleted = &deleted
+		}
+	}
+
+	return stats
+}
+
+func newLogEntry(commit *libocitree.Commit) logEntry {
+	return logEntry{
+		ID:        commit.ID(),
+		ShortID:   shortID(commit.ID()),
+		Date:      commit.CreationDate(),
+		Size:      commit.Size(),
+		Tags:      commit.Tags(),
+		Operation: commit.Operation().String(),
+		CreatedBy: commit.CreatedBy(),
+		Message:   commit.Message(),
+		Subject:   commit.Subject(),
+		Stat:      nil,
+	}
 }
 
 var logCmd = &cobra.Command{
@@ -34,6 +106,35 @@ var logCmd = &cobra.Command{
 			return err
 		}
 
+		flags := cmd.Flags()
+		oneline, _ := flags.GetBool("oneline")
+		format, _ := flags.GetString("format")
+		asJSON, _ := flags.GetBool("json")
+		stat, _ := flags.GetBool("stat")
+
+		outputs := 0
+		for _, isSet := range []bool{oneline, format != "", asJSON} {
+			if isSet {
+				outputs++
+			}
+		}
+		if outputs > 1 {
+			return errors.New("--oneline, --format and --json are mutually exclusive")
+		}
+
+		var tmpl *template.Template
+		if format != "" {
+			tmpl, err = template.New("log").Parse(format)
+			if err != nil {
+				return fmt.Errorf("invalid format template: %w", err)
+			}
+		}
+
+		filter, err := logFilterFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
 		store, err := containersStore()
 		if err != nil {
 			logrus.Errorf("failed to create containers store: %v", err)
@@ -57,17 +158,150 @@ var logCmd = &cobra.Command{
 			logrus.Errorf("failed to list commits of %q: %v", repoName, err)
 			os.Exit(1)
 		}
+		commits = commits.Filter(filter)
+
+		// commitChanges returns changes of the given commit if --stat is set.
+		commitChanges := func(commit *libocitree.Commit) []libocitree.FileChange {
+			if !stat {
+				return nil
+			}
 
-		fmt.Println(repoName)
-		for _, commit := range commits {
-			fmt.Printf("commit %v (%v) %v\n", commit.ID(), units.BytesSize(float64(commit.Size())), commit.Tags())
-			fmt.Printf("Date %v\n", commit.CreationDate().Format(time.RubyDate))
-			if comment := commit.Message(); comment != "" {
-				fmt.Printf("	%v\n", comment)
+			changes, err := repo.CommitChanges(commit, libocitree.DiffOptions{Content: true})
+			if errors.Is(err, libocitree.ErrCommitHasNoImageAssociated) {
+				return nil
+			}
+			if err != nil {
+				logrus.Errorf("failed to compute changes of commit %v: %v", commit.ID(), err)
+				os.Exit(1)
+			}
+
+			return changes
+		}
+
+		if asJSON {
+			entries := make([]logEntry, len(commits))
+			for i := range commits {
+				entries[i] = newLogEntry(&commits[i])
+				if changes := commitChanges(&commits[i]); len(changes) > 0 {
+					entries[i].Stat = newLogFileStats(changes)
+				}
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(entries)
+			if err != nil {
+				logrus.Errorf("failed to encode commits: %v", err)
+				os.Exit(1)
+			}
+
+			return nil
+		}
+
+		if !oneline && tmpl == nil {
+			fmt.Println(repoName)
+		}
+		for i := range commits {
+			commit := &commits[i]
+			changes := commitChanges(commit)
+
+			switch {
+			case oneline:
+				subject := commit.Subject()
+				if subject == "" {
+					subject = commit.CreatedBy()
+				}
+				fmt.Printf("%v %v\n", shortID(commit.ID()), subject)
+
+			case tmpl != nil:
+				entry := newLogEntry(commit)
+				if len(changes) > 0 {
+					entry.Stat = newLogFileStats(changes)
+				}
+				err = tmpl.Execute(os.Stdout, entry)
+				if err != nil {
+					logrus.Errorf("failed to format commit %v: %v", commit.ID(), err)
+					os.Exit(1)
+				}
+				fmt.Println()
+
+			default:
+				fmt.Printf("commit %v (%v) %v\n", commit.ID(), units.BytesSize(float64(commit.Size())), commit.Tags())
+				fmt.Printf("Date %v\n", commit.CreationDate().Format(time.RubyDate))
+				if comment := commit.Message(); comment != "" {
+					fmt.Printf("	%v\n", comment)
+				}
+				fmt.Printf("	%v\n\n", commit.CreatedBy())
+			}
+
+			if len(changes) > 0 {
+				printStat(os.Stdout, changes)
+				fmt.Println()
 			}
-			fmt.Printf("	%v\n\n", commit.CreatedBy())
 		}
 
 		return nil
 	},
 }
+
+// logFilterFromFlags returns the log filter defined by log command flags.
+func logFilterFromFlags(cmd *cobra.Command) (libocitree.LogFilter, error) {
+	flags := cmd.Flags()
+	maxCount, _ := flags.GetInt("max-count")
+	rawSince, _ := flags.GetString("since")
+	rawUntil, _ := flags.GetString("until")
+	rawOperations, _ := flags.GetStringArray("operation")
+	rawGrep, _ := flags.GetString("grep")
+	ocitreeOnly, _ := flags.GetBool("ocitree-only")
+
+	filter := libocitree.LogFilter{
+		MaxCount:    maxCount,
+		Since:       nil,
+		Until:       nil,
+		Operations:  nil,
+		Grep:        nil,
+		OcitreeOnly: ocitreeOnly,
+	}
+
+	if maxCount < 0 {
+		return filter, errors.New("max count must be positive")
+	}
+
+	for _, date := range []struct {
+		raw  string
+		dest **time.Time
+	}{
+		{rawSince, &filter.Since},
+		{rawUntil, &filter.Until},
+	} {
+		if date.raw == "" {
+			continue
+		}
+
+		parsed, err := reference.DateFromString(date.raw)
+		if err != nil {
+			return filter, fmt.Errorf("date %q invalid: %w", date.raw, err)
+		}
+		*date.dest = &parsed
+	}
+
+	for _, rawOps := range rawOperations {
+		for _, rawOp := range strings.Split(rawOps, "|") {
+			op := libocitree.CommitOperationFromString(rawOp)
+			if op == libocitree.UnknownCommitOperation {
+				return filter, fmt.Errorf("unknown commit operation %q", rawOp)
+			}
+			filter.Operations = append(filter.Operations, op)
+		}
+	}
+
+	if rawGrep != "" {
+		grep, err := regexp.Compile(rawGrep)
+		if err != nil {
+			return filter, fmt.Errorf("invalid grep regular expression: %w", err)
+		}
+		filter.Grep = grep
+	}
+
+	return filter, nil
+}
@@ -19,7 +19,13 @@ func init() {
 
 var mountCmd = &cobra.Command{
 	Use:   "mount",
-	Short: "Mount a repository and print mountpoint.",
+	Short: "Mount a repository working container and print mountpoint.",
+	Long: `Mount a repository working container and print mountpoint.
+
+The mountpoint is a writable working container based on HEAD, not the HEAD image
+itself. Changes made in it are kept after unmounting and can be committed using
+"ocitree commit" or discarded using "ocitree reset --hard". Commands moving HEAD
+refuse to run while the working container has uncommitted changes.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return errors.New("a repository name must be specified")
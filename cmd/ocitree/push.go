@@ -0,0 +1,81 @@
+package ocitree
+
+import (
+	"errors"
+	"os"
+
+	"github.com/containers/image/v5/pkg/compression"
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+	flagset := pushCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.Bool("tags", false, "push every tags of the repository instead of HEAD")
+	flagset.String("compression", "", `compression algorithm of layers, one of "gzip", "zstd"`)
+	flagset.String("dest", "", `transport prefixed destination suffixed with pushed tag (e.g. "oci:/path/to/layout")`)
+	flagset.Uint("retry", 0, "number of times to retry in case of failure")
+}
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Push HEAD or tags of a repository to a remote registry.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository reference must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+
+		remoteRef, err := reference.RemoteRefFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		allTags, _ := flags.GetBool("tags")
+		dest, _ := flags.GetString("dest")
+		retry, _ := flags.GetUint("retry")
+
+		var compressionAlgo *compression.Algorithm
+		if name, _ := flags.GetString("compression"); name != "" {
+			algo, err := compression.AlgorithmByName(name)
+			if err != nil {
+				return err
+			}
+			compressionAlgo = &algo
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		err = manager.Push(remoteRef, libocitree.PushOptions{
+			MaxRetries:   retry,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+			Compression:  compressionAlgo,
+			AllTags:      allTags,
+			Destination:  dest,
+		})
+		if err != nil {
+			logrus.Errorf("failed to push repository %q: %v", remoteRef.Name(), err)
+			os.Exit(1)
+		}
+
+		return nil
+	},
+}
@@ -2,6 +2,7 @@ package ocitree
 
 import (
 	"errors"
+	"fmt"
 	"os"
 
 	"github.com/negrel/ocitree/pkg/libocitree"
@@ -16,6 +17,12 @@ func init() {
 	setupStoreOptionsFlags(flagset)
 	setupCommitOptionsFlags(flagset)
 	flagset.BoolP("interactive", "i", false, "List commit to be rebase and let user edit that list before rebasing.")
+	flagset.Bool("autosquash", false, `Move commits whose message starts with "fixup! " or "squash! " after the commit they target and mark them accordingly.`)
+	flagset.Bool("continue", false, "Restart the rebasing process of the given repository after an interruption.")
+	flagset.Bool("abort", false, "Abort the rebase operation of the given repository and restore original HEAD.")
+	flagset.Bool("skip", false, "Restart the rebasing process of the given repository by skipping the current commit.")
+	flagset.String("strategy", "", `Resolve conflicts with new base using the given strategy, one of "ours", "theirs". Rebase stops on conflicts by default. Used with --continue, only conflicts of the interrupted commit are resolved.`)
+	flagset.Bool("replay", false, "Rebuild EXEC and ADD commits by running their command or copying their sources again instead of applying their layer diff.")
 }
 
 var rebaseCmd = &cobra.Command{
@@ -28,17 +35,36 @@ var rebaseCmd = &cobra.Command{
 		if len(args) > 1 {
 			return errors.New("too many arguments specified")
 		}
+
+		flags := cmd.Flags()
+		rawStrategy, _ := flags.GetString("strategy")
+		strategy := libocitree.RebaseConflictStrategyFromString(rawStrategy)
+		if strategy == libocitree.UnknownRebaseConflictStrategy {
+			return fmt.Errorf("unknown conflict strategy %q", rawStrategy)
+		}
+
+		for _, action := range []string{"continue", "abort", "skip"} {
+			if isSet, _ := flags.GetBool(action); isSet {
+				repoName, err := reference.NameFromString(args[0])
+				if err != nil {
+					return err
+				}
+
+				os.Exit(rebaseInProgress(repoName, action, strategy))
+			}
+		}
+
 		rebaseRef, err := reference.RelativeFromString(args[0])
 		if err != nil {
 			return err
 		}
 
-		os.Exit(rebase(cmd, args, rebaseRef))
+		os.Exit(rebase(cmd, args, rebaseRef, strategy))
 		return nil
 	},
 }
 
-func rebase(cmd *cobra.Command, args []string, relRebaseRef reference.Relative) int {
+func rebase(cmd *cobra.Command, args []string, relRebaseRef reference.Relative, strategy libocitree.RebaseConflictStrategy) int {
 	store, err := containersStore()
 	if err != nil {
 		logrus.Errorf("failed to create containers store: %v", err)
@@ -68,6 +94,16 @@ func rebase(cmd *cobra.Command, args []string, relRebaseRef reference.Relative)
 		logrus.Errorf("failed to start rebase session using reference %q: %v", relRebaseRef, err)
 		return 1
 	}
+	session.SetConflictStrategy(strategy)
+	session.SetOutput(os.Stdout, os.Stderr)
+
+	if replay, _ := cmd.Flags().GetBool("replay"); replay {
+		session.SetReplay(true)
+	}
+
+	if autosquash, _ := cmd.Flags().GetBool("autosquash"); autosquash {
+		session.Autosquash()
+	}
 
 	// Interactive session
 	if isInteractive, _ := cmd.Flags().GetBool("interactive"); isInteractive {
@@ -81,8 +117,74 @@ func rebase(cmd *cobra.Command, args []string, relRebaseRef reference.Relative)
 	err = session.Apply()
 	if err != nil {
 		logrus.Errorf("failed to apply rebase: %v", err)
+		if repo.RebaseInProgress() {
+			printRebaseResumeHint(repo.Name(), err)
+		}
 		return 1
 	}
 
 	return 0
 }
+
+func rebaseInProgress(repoName reference.Name, action string, strategy libocitree.RebaseConflictStrategy) int {
+	store, err := containersStore()
+	if err != nil {
+		logrus.Errorf("failed to create containers store: %v", err)
+		return 1
+	}
+
+	manager, err := libocitree.NewManagerFromStore(store, nil)
+	if err != nil {
+		logrus.Errorf("failed to create repository manager: %v", err)
+		return 1
+	}
+
+	repo, err := manager.Repository(repoName)
+	if err != nil {
+		logrus.Errorf("repository not found: %v", err)
+		return 1
+	}
+
+	session, err := repo.RebaseSessionInProgress()
+	if err != nil {
+		logrus.Errorf("failed to restore rebase session: %v", err)
+		return 1
+	}
+	session.SetOutput(os.Stdout, os.Stderr)
+
+	if strategy != libocitree.FailRebaseConflictStrategy {
+		if action == "continue" {
+			session.SetStepConflictStrategy(strategy)
+		} else {
+			session.SetConflictStrategy(strategy)
+		}
+	}
+
+	switch action {
+	case "continue":
+		err = session.Continue()
+	case "abort":
+		err = session.Abort()
+	case "skip":
+		err = session.Skip()
+	}
+	if err != nil {
+		logrus.Errorf("failed to %v rebase: %v", action, err)
+		if repo.RebaseInProgress() {
+			printRebaseResumeHint(repoName, err)
+		}
+		return 1
+	}
+
+	return 0
+}
+
+func printRebaseResumeHint(repoName reference.Name, err error) {
+	fmt.Fprintf(os.Stderr, "Resolve the issue and run \"%[1]v rebase --continue %[2]v\".\n", os.Args[0], repoName)
+	conflictErr := &libocitree.RebaseConflictError{}
+	if errors.As(err, &conflictErr) {
+		fmt.Fprintf(os.Stderr, "To resolve conflicts of the current commit, run \"%[1]v rebase --continue --strategy=ours|theirs %[2]v\".\n", os.Args[0], repoName)
+	}
+	fmt.Fprintf(os.Stderr, "To skip the current commit, run \"%[1]v rebase --skip %[2]v\".\n", os.Args[0], repoName)
+	fmt.Fprintf(os.Stderr, "To abort and restore HEAD, run \"%[1]v rebase --abort %[2]v\".\n", os.Args[0], repoName)
+}
@@ -0,0 +1,71 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(reflogCmd)
+	flagset := reflogCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+}
+
+var reflogCmd = &cobra.Command{
+	Use:   "reflog",
+	Short: "Show movements of repository HEAD.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		entries, err := repo.Reflog()
+		if err != nil {
+			logrus.Errorf("failed to read reflog: %v", err)
+			os.Exit(1)
+		}
+
+		for i, entry := range entries {
+			fmt.Printf("%v HEAD@{%d} %v %v: %v\n",
+				shortID(entry.NewID),
+				i,
+				entry.Timestamp.Format("2006-01-02 15:04:05"),
+				entry.Operation,
+				entry.Message,
+			)
+		}
+
+		return nil
+	},
+}
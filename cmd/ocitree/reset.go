@@ -0,0 +1,92 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(resetCmd)
+	flagset := resetCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	flagset.Bool("soft", false, "Keep changes of discarded commits in the working container so the next commit picks them up.")
+	flagset.Bool("mixed", false, "Discard commits but keep uncommitted changes of the working container (default).")
+	flagset.Bool("hard", false, "Discard commits and uncommitted changes of the working container.")
+}
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset HEAD to the given reference.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository reference must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+
+		mode := ""
+		for _, m := range []string{"soft", "mixed", "hard"} {
+			if isSet, _ := cmd.Flags().GetBool(m); isSet {
+				if mode != "" {
+					return errors.New("only one of --soft, --mixed and --hard can be specified")
+				}
+				mode = m
+			}
+		}
+		resetMode := libocitree.ResetModeFromString(mode)
+
+		relRef, err := reference.RelativeFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		ref, err := manager.ResolveRelativeReference(relRef)
+		if err != nil {
+			logrus.Errorf("failed to resolve relative reference: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(ref.Name())
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		discarded, err := repo.Reset(ref, resetMode)
+		if err != nil {
+			logrus.Errorf("failed to reset repository %q to %q: %v", ref.Name(), relRef, err)
+			os.Exit(1)
+		}
+
+		for _, commit := range discarded {
+			subject := strings.SplitN(strings.TrimSpace(commit.Message()), "\n", 2)[0]
+			fmt.Printf("Discarded commit %v %v %v\n", shortID(commit.ID()), commit.Operation(), subject)
+		}
+		if resetMode == libocitree.SoftResetMode && len(discarded) > 0 {
+			fmt.Printf("Changes of discarded commits were kept, run \"%v commit %v\" to commit them.\n", os.Args[0], ref.Name())
+		}
+		fmt.Printf("HEAD is now at %v\n", repo.ID()[:16])
+
+		return nil
+	},
+}
@@ -0,0 +1,85 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(revertCmd)
+	flagset := revertCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupCommitOptionsFlags(flagset)
+	flagset.String("strategy", "", `Resolve conflicts with later commits using the given strategy, one of "ours", "theirs". Revert fails on conflicts by default.`)
+}
+
+var revertCmd = &cobra.Command{
+	Use:   "revert",
+	Short: "Commit the inverse of an existing commit.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository reference must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+
+		flags := cmd.Flags()
+		rawStrategy, _ := flags.GetString("strategy")
+		strategy := libocitree.RebaseConflictStrategyFromString(rawStrategy)
+		if strategy == libocitree.UnknownRebaseConflictStrategy {
+			return fmt.Errorf("unknown conflict strategy %q", rawStrategy)
+		}
+
+		relRef, err := reference.RelativeFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		ref, err := manager.ResolveRelativeReference(relRef)
+		if err != nil {
+			logrus.Errorf("failed to resolve relative reference: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(ref.Name())
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		message, _ := flags.GetString("message")
+
+		err = repo.Revert(ref, libocitree.RevertOptions{
+			Message:          message,
+			ConflictStrategy: strategy,
+			ReportWriter:     os.Stderr,
+		})
+		if err != nil {
+			logrus.Errorf("failed to revert %q: %v", relRef, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("HEAD is now at %v\n", shortID(repo.ID()))
+
+		return nil
+	},
+}
@@ -0,0 +1,110 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+	flagset := showCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+	setupDiffOptionsFlags(flagset)
+	flagset.BoolP("patch", "p", false, "Show content diff of changed files.")
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show a commit and the files it changed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository reference must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+
+		relRef, err := reference.RelativeFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		ref, err := manager.ResolveRelativeReference(relRef)
+		if err != nil {
+			logrus.Errorf("failed to resolve relative reference: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(ref.Name())
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		commit, err := repo.Commit(ref)
+		if err != nil {
+			logrus.Errorf("failed to retrieve commit %q: %v", relRef, err)
+			os.Exit(1)
+		}
+
+		printCommit(commit)
+
+		patch, _ := cmd.Flags().GetBool("patch")
+		changes, err := repo.CommitChanges(commit, libocitree.DiffOptions{
+			Content: patch || diffOpts.stat,
+		})
+		if err != nil {
+			logrus.Errorf("failed to compute changes of commit %q: %v", relRef, err)
+			os.Exit(1)
+		}
+
+		fmt.Println()
+		switch {
+		case patch:
+			printPatch(os.Stdout, changes)
+		case diffOpts.stat:
+			printStat(os.Stdout, changes)
+		default:
+			printNameStatus(os.Stdout, changes)
+		}
+
+		return nil
+	},
+}
+
+func printCommit(commit *libocitree.Commit) {
+	fmt.Printf("commit %v (%v) %v\n", commit.ID(), units.BytesSize(float64(commit.Size())), commit.Tags())
+	if date := commit.CreationDate(); date != nil {
+		fmt.Printf("Date      %v\n", date.Format(time.RubyDate))
+	}
+	fmt.Printf("Operation %v\n", commit.Operation())
+	fmt.Printf("CreatedBy %v\n", commit.CreatedBy())
+
+	if message := strings.TrimSpace(commit.Message()); message != "" {
+		fmt.Println()
+		for _, line := range strings.Split(message, "\n") {
+			fmt.Printf("	%v\n", line)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ocitree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/negrel/ocitree/pkg/libocitree"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	flagset := statusCmd.PersistentFlags()
+	setupStoreOptionsFlags(flagset)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the repository status and uncommitted changes.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("a repository name must be specified")
+		}
+		if len(args) > 1 {
+			return errors.New("too many arguments specified")
+		}
+		repoName, err := reference.NameFromString(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := containersStore()
+		if err != nil {
+			logrus.Errorf("failed to create containers store: %v", err)
+			os.Exit(1)
+		}
+
+		manager, err := libocitree.NewManagerFromStore(store, nil)
+		if err != nil {
+			logrus.Errorf("failed to create repository manager: %v", err)
+			os.Exit(1)
+		}
+
+		repo, err := manager.Repository(repoName)
+		if err != nil {
+			logrus.Errorf("repository not found: %v", err)
+			os.Exit(1)
+		}
+
+		status, err := repo.Status()
+		if err != nil {
+			logrus.Errorf("failed to retrieve repository status: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("On repository %v\n", repoName)
+		fmt.Printf("HEAD at %v %v\n", status.HeadID[:16], status.HeadTags)
+
+		if status.RebaseInProgress {
+			fmt.Println("Rebase in progress")
+			printRebaseResumeHint(repoName, nil)
+		}
+
+		if status.IsMounted() {
+			fmt.Printf("Mounted at %v\n", status.Mountpoint)
+		} else {
+			fmt.Println("Not mounted")
+		}
+
+		if status.WorkingContainerOutdated {
+			fmt.Println("Working container isn't based on HEAD, changes can't be committed")
+		}
+
+		fmt.Println()
+		if status.IsClean() {
+			fmt.Println("nothing to commit, working container clean")
+			return nil
+		}
+
+		fmt.Println("Changes to be committed:")
+		for _, change := range status.Changes {
+			fmt.Printf("	%-9v %v\n", change.Kind.String()+":", change.Path)
+		}
+
+		return nil
+	},
+}
@@ -18,7 +18,11 @@ func init() {
 
 var umountCmd = &cobra.Command{
 	Use:   "umount",
-	Short: "Unmount a repository.",
+	Short: "Unmount a repository working container.",
+	Long: `Unmount a repository working container.
+
+The working container and its uncommitted changes are kept, use "ocitree commit"
+to commit them or "ocitree reset --hard" to discard them.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return errors.New("a repository name must be specified")
@@ -0,0 +1,115 @@
+package libocitree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+var (
+	ErrCherryPickNotOcitreeCommit = errors.New("only commits created by ocitree can be cherry-picked")
+)
+
+const cherryPickTrailerPrefix = "(cherry picked from commit "
+
+// CherryPickOptions holds options for Repository.CherryPick method.
+type CherryPickOptions struct {
+	// ConflictStrategy define how conflicts between picked commits and
+	// changes made on HEAD are resolved.
+	ConflictStrategy RebaseConflictStrategy
+
+	ReportWriter io.Writer
+}
+
+// CherryPick applies commits with the given references on top of HEAD, in the
+// given order. References may point to commits of other repositories. Picked
+// commits keep their operation and message, a trailer recording the picked
+// commit is appended to the message. Cherry-pick stops on the first failing
+// commit, commits picked before it are kept.
+func (r *Repository) CherryPick(options CherryPickOptions, refs ...reference.Reference) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		err := r.cherryPick(ref, options)
+		if err != nil {
+			return fmt.Errorf("failed to cherry-pick %v: %w", ref, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) cherryPick(ref reference.Reference, options CherryPickOptions) error {
+	commit, err := r.Commit(ref)
+	if err != nil {
+		return err
+	}
+	if !commit.WasCreatedByOcitree() {
+		return ErrCherryPickNotOcitreeCommit
+	}
+
+	parent := commit.Parent()
+	if parent == nil || !hasImage(parent) {
+		return fmt.Errorf("failed to retrieve parent of commit %v: %w", commit.ID(), ErrCommitHasNoImageAssociated)
+	}
+
+	builder, err := r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	err = pick(r.runtime, builder, commit, func(mountpoint string, layer []byte) ([]byte, error) {
+		if parent.ID() == r.ID() {
+			return layer, nil
+		}
+
+		// Picked commit may come from an unrelated repository, only paths
+		// touched by its layer are compared between its parent and HEAD.
+		parentMountpoint, err := r.runtime.mountImage(parent.ID())
+		if err != nil {
+			return nil, err
+		}
+		defer r.runtime.unmountImage(parent.ID())
+
+		headChanges, err := layerPathsChanges(parentMountpoint, mountpoint, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute changes between commit %v and HEAD: %w", parent.ID(), err)
+		}
+
+		return resolveConflicts(mountpoint, commit.ID(), layer, headChanges, options.ConflictStrategy)
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.commit(builder, CherryPickReflogOperation, CommitOptions{
+		CreatedBy:    commit.CreatedBy()[len(CommitPrefix):],
+		Message:      cherryPickMessage(commit.Message(), ref.Name(), commit.ID()),
+		Squash:       false,
+		Record:       commit.operationRecord(),
+		ReportWriter: options.ReportWriter,
+	})
+}
+
+// cherryPickMessage appends a trailer recording the picked commit to the given
+// message.
+func cherryPickMessage(message string, name reference.Name, id string) string {
+	trailer := fmt.Sprintf("%v%v%v%v)", cherryPickTrailerPrefix, name, reference.IdPrefix, id)
+
+	message = strings.TrimRight(message, "\n")
+	if message == "" {
+		return trailer
+	}
+
+	return message + "\n\n" + trailer
+}
@@ -0,0 +1,164 @@
+package libocitree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryCherryPick(t *testing.T) {
+	manager, cleanup, repo, resetRef := setupResetTest(t)
+	defer cleanup()
+
+	commit2Ref, err := manager.ResolveRelativeReference(reference.RelativeFromReferenceAndOffset(repo.HeadRef(), 1))
+	require.NoError(t, err)
+	commit2, err := repo.Commit(commit2Ref)
+	require.NoError(t, err)
+
+	_, err = repo.Reset(resetRef, HardResetMode)
+	require.NoError(t, err)
+
+	t.Run("Valid", func(t *testing.T) {
+		err := repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, commit2Ref)
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, commit2.CreatedBy(), commits[0].CreatedBy())
+		require.Equal(t, ExecCommitOperation, commits[0].Operation())
+		require.Equal(t, "commit 2\n\n(cherry picked from commit "+commit2Ref.String()+")", commits[0].Message())
+		require.Equal(t, commit2Ref.String(), commits[0].CherryPickedFrom())
+		require.Equal(t, resetRef.IdOrTag()[len(reference.IdPrefix):], commits[0].Parent().ID())
+
+		requireWorkingContainerFiles(t, repo, []string{"commit2"}, []string{"commit1", "commit3"})
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		// commit2 was picked already
+		err := repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, commit2Ref)
+		require.Error(t, err)
+
+		var conflictErr *RebaseConflictError
+		require.True(t, errors.As(err, &conflictErr))
+	})
+
+	t.Run("NotOcitreeCommit", func(t *testing.T) {
+		err := repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, resetRef)
+		require.ErrorIs(t, err, ErrCherryPickNotOcitreeCommit)
+	})
+}
+
+// initTestRepository creates a new repository whose first commit contains the
+// given files with the given modification time.
+func initTestRepository(t *testing.T, manager *Manager, rawName string, modTime time.Time, files map[string]string) *Repository {
+	name, err := reference.NameFromString(rawName)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, p), []byte(content), 0644))
+		require.NoError(t, os.Chtimes(filepath.Join(dir, p), modTime, modTime))
+	}
+
+	err = manager.Init(name, InitOptions{
+		FromDir:      dir,
+		FromTar:      "",
+		Message:      "",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(name)
+	require.NoError(t, err)
+
+	return repo
+}
+
+func TestRepositoryCherryPickOtherRepository(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	// Unrelated repositories, created at different times, with a common file.
+	other := initTestRepository(t, manager, "localhost/ocitree/other", time.Unix(1000, 0), map[string]string{
+		"etc/hostname": "other\n",
+		"shared":       "shared\n",
+		"other":        "other\n",
+	})
+	repo := initTestRepository(t, manager, "localhost/ocitree/repo", time.Unix(2000, 0), map[string]string{
+		"etc/hostname": "repo\n",
+		"shared":       "shared\n",
+		"repo":         "repo\n",
+	})
+
+	addToOther := func(dest, content string) reference.Reference {
+		src := filepath.Join(t.TempDir(), "src")
+		require.NoError(t, os.WriteFile(src, []byte(content), 0644))
+
+		err := other.Add(dest, AddOptions{
+			Chmod:        "",
+			Chown:        "",
+			Message:      "add " + dest,
+			ReportWriter: nil,
+		}, src)
+		require.NoError(t, err)
+
+		id, err := reference.IDFromString(other.ID())
+		require.NoError(t, err)
+
+		return reference.NewLocal(other.Name(), id)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		// Picked layer touches a new file and a file identical in both
+		// repositories.
+		addToOther("/picked", "picked\n")
+		commitRef := addToOther("/shared", "shared\n")
+
+		err := repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, commitRef)
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, AddCommitOperation, commits[0].Operation())
+		require.Equal(t, commitRef.String(), commits[0].CherryPickedFrom())
+
+		require.Equal(t, "shared\n", readRepoFile(t, repo, "/shared"))
+		require.Equal(t, "repo\n", readRepoFile(t, repo, "/etc/hostname"))
+		requireWorkingContainerFiles(t, repo, []string{"repo"}, []string{"other", "picked"})
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		// /etc/hostname differs between picked commit parent and HEAD.
+		commitRef := addToOther("/etc/hostname", "picked\n")
+		headID := repo.ID()
+
+		err := repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, commitRef)
+
+		var conflictErr *RebaseConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		require.Len(t, conflictErr.Conflicts, 1)
+		require.Equal(t, "/etc/hostname", conflictErr.Conflicts[0].Path)
+		require.Equal(t, headID, repo.ID())
+	})
+}
@@ -18,14 +18,38 @@ const (
 	UnknownCommitOperation CommitOperation = iota
 	ExecCommitOperation
 	AddCommitOperation
+	CommitCommitOperation
+	RevertCommitOperation
+	FlattenCommitOperation
+	InitCommitOperation
+	ImportCommitOperation
+	ConfigCommitOperation
 )
 
+// CommitOperationFromString parses the given commit operation, case
+// insensitively.
+func CommitOperationFromString(str string) CommitOperation {
+	return commitOperationFromString(strings.ToUpper(str))
+}
+
 func commitOperationFromString(str string) CommitOperation {
 	switch str {
 	case "EXEC":
 		return ExecCommitOperation
 	case "ADD":
 		return AddCommitOperation
+	case "COMMIT":
+		return CommitCommitOperation
+	case "REVERT":
+		return RevertCommitOperation
+	case "FLATTEN":
+		return FlattenCommitOperation
+	case "INIT":
+		return InitCommitOperation
+	case "IMPORT":
+		return ImportCommitOperation
+	case "CONFIG":
+		return ConfigCommitOperation
 	default:
 		return UnknownCommitOperation
 	}
@@ -38,6 +62,18 @@ func (co CommitOperation) String() string {
 		return "EXEC"
 	case AddCommitOperation:
 		return "ADD"
+	case CommitCommitOperation:
+		return "COMMIT"
+	case RevertCommitOperation:
+		return "REVERT"
+	case FlattenCommitOperation:
+		return "FLATTEN"
+	case InitCommitOperation:
+		return "INIT"
+	case ImportCommitOperation:
+		return "IMPORT"
+	case ConfigCommitOperation:
+		return "CONFIG"
 	default:
 		return "UNKNOWN"
 	}
@@ -63,6 +99,18 @@ func newCommits(history []libimage.ImageHistory) Commits {
 	return commits
 }
 
+// indexOf returns the index of the commit with the given ID or -1 if
+// there is none.
+func (c Commits) indexOf(id string) int {
+	for i := range c {
+		if c[i].ID() == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // Commit define the history of a single layer.
 type Commit struct {
 	history libimage.ImageHistory
@@ -82,11 +130,23 @@ func (c *Commit) ID() string {
 
 // Message returns the message associated to this commit.
 func (c *Commit) Message() string {
+	message, _ := splitHistoryComment(c.comment())
+	return message
+}
+
+// Subject returns the first line of the message associated to this commit.
+func (c *Commit) Subject() string {
+	return messageSubject(c.Message())
+}
+
+// comment returns the history comment of this commit without base image line.
+func (c *Commit) comment() string {
 	if splitted := strings.Split(c.history.Comment, "\nFROM"); len(splitted) != 1 {
 		return splitted[0]
 	}
 
-	return c.history.Comment
+	// Comment of commits based on scratch isn't followed by FROM.
+	return strings.TrimSuffix(c.history.Comment, "\n")
 }
 
 // Tags returns the tags associated to this commit.
@@ -121,10 +181,42 @@ func (c *Commit) Operation() CommitOperation {
 		return UnknownCommitOperation
 	}
 
+	if record := c.operationRecord(); record != nil {
+		return record.CommitOperation()
+	}
+
 	splitted := strings.SplitN(c.history.CreatedBy[len(CommitPrefix):], " ", 2)
 	return commitOperationFromString(splitted[0])
 }
 
+// RevertedID returns the ID of the commit reverted by this commit or an empty
+// string if this commit isn't a revert commit.
+func (c *Commit) RevertedID() string {
+	if c.Operation() != RevertCommitOperation {
+		return ""
+	}
+
+	splitted := strings.SplitN(c.history.CreatedBy[len(CommitPrefix):], " ", 3)
+	if len(splitted) < 2 {
+		return ""
+	}
+
+	return splitted[1]
+}
+
+// CherryPickedFrom returns the reference of the commit this commit was
+// cherry-picked from or an empty string if it wasn't cherry-picked.
+func (c *Commit) CherryPickedFrom() string {
+	message := strings.TrimRight(c.Message(), "\n")
+	lines := strings.Split(message, "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, cherryPickTrailerPrefix) || !strings.HasSuffix(last, ")") {
+		return ""
+	}
+
+	return last[len(cherryPickTrailerPrefix) : len(last)-1]
+}
+
 // Parent returns the parent commit.
 func (c *Commit) Parent() *Commit {
 	return c.parent
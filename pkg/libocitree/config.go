@@ -0,0 +1,178 @@
+package libocitree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containers/buildah"
+)
+
+var (
+	ErrConfigNoChanges       = errors.New("no configuration changes specified")
+	ErrConfigInvalidKeyValue = errors.New("invalid KEY=VALUE pair")
+	ErrNotConfigCommit       = errors.New("commit is not a config commit")
+)
+
+// ConfigChanges define changes made to the image configuration by a config
+// commit. Zero values leave the configuration unchanged.
+type ConfigChanges struct {
+	// Env holds KEY=VALUE environment variables to set.
+	Env []string `json:"env,omitempty"`
+	// Entrypoint and Cmd are left unchanged if nil and cleared if empty, they
+	// are always serialized so replayed commits keep the distinction.
+	Entrypoint []string `json:"entrypoint"`
+	Cmd        []string `json:"cmd"`
+	WorkDir    string   `json:"workdir,omitempty"`
+	User       string   `json:"user,omitempty"`
+	// Labels holds KEY=VALUE labels to set.
+	Labels []string `json:"labels,omitempty"`
+	// Expose holds ports to expose using the port[/protocol] format.
+	Expose []string `json:"expose,omitempty"`
+}
+
+// IsEmpty returns true if there is no changes.
+func (cc ConfigChanges) IsEmpty() bool {
+	return len(cc.Env) == 0 && cc.Entrypoint == nil && cc.Cmd == nil &&
+		cc.WorkDir == "" && cc.User == "" && len(cc.Labels) == 0 && len(cc.Expose) == 0
+}
+
+// apply applies changes to builder configuration.
+func (cc ConfigChanges) apply(builder *buildah.Builder) error {
+	for _, env := range cc.Env {
+		key, value, ok := strings.Cut(env, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("%w: %q", ErrConfigInvalidKeyValue, env)
+		}
+		builder.SetEnv(key, value)
+	}
+
+	for _, label := range cc.Labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("%w: %q", ErrConfigInvalidKeyValue, label)
+		}
+		builder.SetLabel(key, value)
+	}
+
+	if cc.Entrypoint != nil {
+		builder.SetEntrypoint(cc.Entrypoint)
+	}
+	if cc.Cmd != nil {
+		builder.SetCmd(cc.Cmd)
+	}
+	if cc.WorkDir != "" {
+		builder.SetWorkDir(cc.WorkDir)
+	}
+	if cc.User != "" {
+		builder.SetUser(cc.User)
+	}
+	for _, port := range cc.Expose {
+		builder.SetPort(port)
+	}
+
+	return nil
+}
+
+// configChangesFromCreatedBy parses configuration changes of a config commit
+// CreatedBy string.
+func configChangesFromCreatedBy(createdBy string) (ConfigChanges, error) {
+	createdBy = strings.TrimPrefix(createdBy, CommitPrefix)
+	rawChanges := strings.TrimPrefix(createdBy, ConfigCommitOperation.String()+" ")
+	if rawChanges == createdBy {
+		return ConfigChanges{}, ErrNotConfigCommit
+	}
+
+	changes := ConfigChanges{}
+	err := json.Unmarshal([]byte(rawChanges), &changes)
+	if err != nil {
+		return ConfigChanges{}, fmt.Errorf("failed to parse configuration changes: %w", err)
+	}
+
+	return changes, nil
+}
+
+// replayConfig applies configuration changes of the commit with the given
+// CreatedBy string on builder if it is a config commit.
+func replayConfig(builder *buildah.Builder, createdBy string) error {
+	changes, err := configChangesFromCreatedBy(createdBy)
+	if errors.Is(err, ErrNotConfigCommit) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return changes.apply(builder)
+}
+
+// ConfigOptions holds options for Repository.Config method.
+type ConfigOptions struct {
+	Changes ConfigChanges
+
+	Message      string
+	ReportWriter io.Writer
+}
+
+// Config commits the given configuration changes on HEAD. Config commits
+// contains an empty layer rather than being flagged as empty layer in history
+// so each of them is associated to its own image.
+func (r *Repository) Config(options ConfigOptions) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	if options.Changes.IsEmpty() {
+		return ErrConfigNoChanges
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
+	rawChanges, err := json.Marshal(options.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to serialize configuration changes: %w", err)
+	}
+
+	builder, err := r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	err = options.Changes.apply(builder)
+	if err != nil {
+		return err
+	}
+
+	record, err := newOperationRecord(ConfigCommitOperation, options.Changes)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(builder, CommitReflogOperation, CommitOptions{
+		CreatedBy:    ConfigCommitOperation.String() + " " + string(rawChanges),
+		Message:      options.Message,
+		Squash:       false,
+		Record:       record,
+		ReportWriter: options.ReportWriter,
+	})
+}
+
+// ConfigChanges returns configuration changes made by this commit. An error
+// is returned if this commit isn't a config commit.
+func (c *Commit) ConfigChanges() (ConfigChanges, error) {
+	if c.Operation() != ConfigCommitOperation {
+		return ConfigChanges{}, ErrNotConfigCommit
+	}
+
+	if record := c.operationRecord(); record != nil && record.Args != nil {
+		changes := ConfigChanges{}
+		err := record.decodeArgs(ConfigCommitOperation, &changes)
+		return changes, err
+	}
+
+	return configChangesFromCreatedBy(c.CreatedBy())
+}
@@ -0,0 +1,204 @@
+package libocitree
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigChangesFromCreatedBy(t *testing.T) {
+	changes := ConfigChanges{
+		Env:        []string{"FOO=bar baz"},
+		Entrypoint: []string{"/bin/sh", "-c", `echo "hello"`},
+		Cmd:        nil,
+		WorkDir:    "/srv",
+		User:       "",
+		Labels:     nil,
+		Expose:     []string{"80/tcp"},
+	}
+
+	rawChanges, err := json.Marshal(changes)
+	require.NoError(t, err)
+	parsed, err := configChangesFromCreatedBy(CommitPrefix + ConfigCommitOperation.String() + " " + string(rawChanges))
+	require.NoError(t, err)
+	require.Equal(t, changes, parsed)
+
+	// Cleared entrypoint and cmd aren't parsed as unchanged.
+	cleared := ConfigChanges{
+		Env:        nil,
+		Entrypoint: []string{},
+		Cmd:        []string{},
+		WorkDir:    "",
+		User:       "",
+		Labels:     nil,
+		Expose:     nil,
+	}
+	rawChanges, err = json.Marshal(cleared)
+	require.NoError(t, err)
+	parsed, err = configChangesFromCreatedBy(CommitPrefix + ConfigCommitOperation.String() + " " + string(rawChanges))
+	require.NoError(t, err)
+	require.Equal(t, cleared, parsed)
+
+	_, err = configChangesFromCreatedBy(CommitPrefix + ExecCommitOperation.String() + ` ["true"]`)
+	require.ErrorIs(t, err, ErrNotConfigCommit)
+}
+
+func TestRepositoryConfig(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	name, err := reference.NameFromString("localhost/ocitree/config")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello"), []byte("world\n"), 0644))
+
+	err = manager.Init(name, InitOptions{
+		FromDir:      dir,
+		FromTar:      "",
+		Message:      "",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(name)
+	require.NoError(t, err)
+	initID := repo.ID()
+
+	changes := ConfigChanges{
+		Env:        []string{"FOO=bar"},
+		Entrypoint: []string{"/bin/app"},
+		Cmd:        []string{"--help"},
+		WorkDir:    "/srv",
+		User:       "1000:1000",
+		Labels:     []string{"maintainer=ocitree"},
+		Expose:     []string{"8080/tcp"},
+	}
+
+	requireHeadConfig := func(t *testing.T) {
+		data, err := repo.head.Inspect(context.Background(), nil)
+		require.NoError(t, err)
+
+		require.Contains(t, data.Config.Env, "FOO=bar")
+		require.Equal(t, []string{"/bin/app"}, data.Config.Entrypoint)
+		require.Equal(t, []string{"--help"}, data.Config.Cmd)
+		require.Equal(t, "/srv", data.Config.WorkingDir)
+		require.Equal(t, "1000:1000", data.Config.User)
+		require.Equal(t, "ocitree", data.Config.Labels["maintainer"])
+		require.Contains(t, data.Config.ExposedPorts, "8080/tcp")
+	}
+
+	t.Run("NoChanges", func(t *testing.T) {
+		err := repo.Config(ConfigOptions{
+			Changes:      ConfigChanges{},
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrConfigNoChanges)
+	})
+
+	t.Run("InvalidEnv", func(t *testing.T) {
+		err := repo.Config(ConfigOptions{
+			Changes:      ConfigChanges{Env: []string{"FOO"}},
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrConfigInvalidKeyValue)
+		require.Equal(t, initID, repo.ID())
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		err := repo.Config(ConfigOptions{
+			Changes:      changes,
+			Message:      "configure app",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, initID, repo.ID())
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, ConfigCommitOperation, commits[0].Operation())
+		require.Equal(t, "configure app", commits[0].Message())
+		require.Equal(t, initID, commits[0].Parent().ID())
+
+		commitChanges, err := commits[0].ConfigChanges()
+		require.NoError(t, err)
+		require.Equal(t, changes, commitChanges)
+
+		requireHeadConfig(t)
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		configID, err := reference.IDFromString(repo.ID())
+		require.NoError(t, err)
+
+		initRef, err := reference.IDFromString(initID)
+		require.NoError(t, err)
+		_, err = repo.Reset(reference.NewLocal(repo.Name(), initRef), HardResetMode)
+		require.NoError(t, err)
+
+		err = repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, reference.NewLocal(repo.Name(), configID))
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, ConfigCommitOperation, commits[0].Operation())
+
+		requireHeadConfig(t)
+	})
+
+	t.Run("ClearReplay", func(t *testing.T) {
+		configID := repo.ID()
+
+		err := repo.Config(ConfigOptions{
+			Changes: ConfigChanges{
+				Env:        nil,
+				Entrypoint: []string{},
+				Cmd:        []string{},
+				WorkDir:    "",
+				User:       "",
+				Labels:     nil,
+				Expose:     nil,
+			},
+			Message:      "clear entrypoint and cmd",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		requireCleared := func() {
+			data, err := repo.head.Inspect(context.Background(), nil)
+			require.NoError(t, err)
+			require.Empty(t, data.Config.Entrypoint)
+			require.Empty(t, data.Config.Cmd)
+			require.Equal(t, "/srv", data.Config.WorkingDir)
+		}
+		requireCleared()
+
+		clearID, err := reference.IDFromString(repo.ID())
+		require.NoError(t, err)
+
+		// Replay clear commit on config commit
+		configRef, err := reference.IDFromString(configID)
+		require.NoError(t, err)
+		_, err = repo.Reset(reference.NewLocal(repo.Name(), configRef), HardResetMode)
+		require.NoError(t, err)
+		requireHeadConfig(t)
+
+		err = repo.CherryPick(CherryPickOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, reference.NewLocal(repo.Name(), clearID))
+		require.NoError(t, err)
+
+		requireCleared()
+	})
+}
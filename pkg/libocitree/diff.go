@@ -0,0 +1,388 @@
+package libocitree
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffContentSizeLimit is the maximum size of a file whose content is loaded
+// in a FileChange.
+const diffContentSizeLimit = 4 << 20
+
+// FileChangeKind define the kind of change of a FileChange.
+type FileChangeKind uint
+
+const (
+	AddedFileChange FileChangeKind = iota
+	ModifiedFileChange
+	DeletedFileChange
+)
+
+// String implements fmt.Stringer.
+func (fck FileChangeKind) String() string {
+	switch fck {
+	case AddedFileChange:
+		return "added"
+	case ModifiedFileChange:
+		return "modified"
+	case DeletedFileChange:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// Short returns the one letter status of the change (A, M or D).
+func (fck FileChangeKind) Short() string {
+	switch fck {
+	case AddedFileChange:
+		return "A"
+	case ModifiedFileChange:
+		return "M"
+	case DeletedFileChange:
+		return "D"
+	default:
+		return "?"
+	}
+}
+
+// FileChange define a file added, modified or deleted between two references.
+type FileChange struct {
+	Path string
+	Kind FileChangeKind
+	// Old is the version of the file in the source reference.
+	Old FileVersion
+	// New is the version of the file in the destination reference.
+	New FileVersion
+
+	// OldContent and NewContent holds content of regular files if
+	// DiffOptions.Content is set and file isn't too large.
+	OldContent []byte
+	NewContent []byte
+}
+
+// IsBinary returns true if old or new content of the file isn't text.
+func (fc FileChange) IsBinary() bool {
+	return isBinary(fc.OldContent) || isBinary(fc.NewContent)
+}
+
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// hasTextContent returns true if old and new content are loaded and are text.
+func (fc FileChange) hasTextContent() bool {
+	hasContent := func(version FileVersion, content []byte) bool {
+		return version.Deleted || (version.Mode.IsRegular() && (content != nil || version.Size == 0))
+	}
+
+	return hasContent(fc.Old, fc.OldContent) && hasContent(fc.New, fc.NewContent) && !fc.IsBinary()
+}
+
+// UnifiedDiff returns a unified diff of the file content. false is returned
+// if the file isn't a text file or if its content wasn't loaded.
+func (fc FileChange) UnifiedDiff() (string, bool) {
+	if !fc.hasTextContent() {
+		return "", false
+	}
+
+	fromFile, toFile := "a"+fc.Path, "b"+fc.Path
+	if fc.Old.Deleted {
+		fromFile = "/dev/null"
+	}
+	if fc.New.Deleted {
+		toFile = "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(fc.OldContent),
+		B:        splitLines(fc.NewContent),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return "", false
+	}
+
+	return diff, true
+}
+
+// LineStats returns the number of lines added and deleted. false is returned
+// if the file isn't a text file or if its content wasn't loaded.
+func (fc FileChange) LineStats() (added int, deleted int, ok bool) {
+	if !fc.hasTextContent() {
+		return 0, 0, false
+	}
+
+	matcher := difflib.NewMatcher(splitLines(fc.OldContent), splitLines(fc.NewContent))
+	for _, op := range matcher.GetOpCodes() {
+		switch op.Tag {
+		case 'r':
+			deleted += op.I2 - op.I1
+			added += op.J2 - op.J1
+		case 'd':
+			deleted += op.I2 - op.I1
+		case 'i':
+			added += op.J2 - op.J1
+		}
+	}
+
+	return added, deleted, true
+}
+
+// splitLines splits the given content into lines, line endings are kept.
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// DiffOptions holds diff options.
+type DiffOptions struct {
+	// Content loads content of changed regular files.
+	Content bool
+}
+
+// Diff returns the files changed between the given references.
+func (r *Repository) Diff(from, to reference.Reference, options DiffOptions) ([]FileChange, error) {
+	fromImg, err := r.runtime.lookupImage(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup %v: %w", from, err)
+	}
+
+	toImg, err := r.runtime.lookupImage(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup %v: %w", to, err)
+	}
+
+	return diffImages(r.runtime, fromImg.ID(), toImg.ID(), options)
+}
+
+// CommitChanges returns the files changed by the given commit.
+func (r *Repository) CommitChanges(commit *Commit, options DiffOptions) ([]FileChange, error) {
+	if !hasImage(commit) {
+		return nil, ErrCommitHasNoImageAssociated
+	}
+
+	// Commits without parent image, such as the top commit of a base image,
+	// are diffed against their parent layer.
+	parentID := ""
+	if parent := commit.Parent(); parent != nil && hasImage(parent) {
+		parentID = parent.ID()
+	} else if parent != nil && commit.Size() == 0 {
+		// Commit has an empty layer, its image top layer belongs to a
+		// parent commit.
+		return []FileChange{}, nil
+	}
+
+	return diffImages(r.runtime, parentID, commit.ID(), options)
+}
+
+func hasImage(commit *Commit) bool {
+	return commit.ID() != "" && commit.ID() != "<missing>"
+}
+
+// diffImages returns the files changed between the given images. An empty
+// fromImageID diff against the parent layer of toImageID top layer, changed
+// files are then reported as added as their previous version isn't loaded.
+func diffImages(runtime imageRuntime, fromImageID, toImageID string, options DiffOptions) ([]FileChange, error) {
+	if fromImageID == toImageID {
+		return []FileChange{}, nil
+	}
+
+	newEntries, err := readImagesDiff(runtime, fromImageID, toImageID, options.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverse diff contains previous version of modified and deleted files.
+	oldEntries := newLayerEntries()
+	if fromImageID != "" {
+		oldEntries, err = readImagesDiff(runtime, toImageID, fromImageID, options.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fileChanges(oldEntries, newEntries), nil
+}
+
+func readImagesDiff(runtime imageRuntime, fromImageID, toImageID string, withContent bool) (layerEntries, error) {
+	diff, err := runtime.diff(fromImageID, toImageID)
+	if err != nil {
+		return layerEntries{}, err
+	}
+	defer diff.Close()
+
+	return readLayerEntries(diff, withContent)
+}
+
+// layerEntry define a file of a layer.
+type layerEntry struct {
+	version FileVersion
+	content []byte
+}
+
+// layerEntries define the files of a layer along with whiteouts paths.
+type layerEntries struct {
+	files     map[string]layerEntry
+	whiteouts []string
+}
+
+func newLayerEntries() layerEntries {
+	return layerEntries{
+		files:     make(map[string]layerEntry),
+		whiteouts: nil,
+	}
+}
+
+// readLayerEntries reads the given layer tar.
+func readLayerEntries(layer io.Reader, withContent bool) (layerEntries, error) {
+	entries := newLayerEntries()
+	tr := tar.NewReader(layer)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return layerEntries{}, fmt.Errorf("failed to read layer: %w", err)
+		}
+
+		p, isWhiteout, isOpaque := layerEntryPath(hdr)
+		// Content of opaque directories missing in the new layer is
+		// reported as deleted using the reverse diff.
+		if isOpaque {
+			continue
+		}
+		if isWhiteout {
+			entries.whiteouts = append(entries.whiteouts, p)
+			continue
+		}
+
+		entry := layerEntry{}
+		if withContent && hdr.Typeflag == tar.TypeReg && hdr.Size <= diffContentSizeLimit {
+			entry.content, err = io.ReadAll(tr)
+			if err != nil {
+				return layerEntries{}, fmt.Errorf("failed to read %v content: %w", p, err)
+			}
+			entry.version = FileVersion{
+				Deleted: false,
+				Mode:    hdr.FileInfo().Mode(),
+				Size:    hdr.Size,
+				Digest:  digest.FromBytes(entry.content),
+			}
+		} else {
+			entry.version, err = fileVersionFromTar(hdr, tr)
+			if err != nil {
+				return layerEntries{}, fmt.Errorf("failed to read %v: %w", p, err)
+			}
+		}
+
+		entries.files[p] = entry
+	}
+
+	return entries, nil
+}
+
+// fileChanges returns the changes contained in newEntries, a diff from old
+// to new rootfs. oldEntries is the reverse diff and holds previous version of
+// modified and deleted files. Whiteouts and opaque directories are reported
+// as deletions. Result is sorted by path.
+func fileChanges(oldEntries, newEntries layerEntries) []FileChange {
+	changes := make([]FileChange, 0, len(newEntries.files))
+	deleted := FileVersion{Deleted: true}
+
+	for p, newEntry := range newEntries.files {
+		oldEntry, exist := oldEntries.files[p]
+		if !exist {
+			changes = append(changes, FileChange{
+				Path:       p,
+				Kind:       AddedFileChange,
+				Old:        deleted,
+				New:        newEntry.version,
+				OldContent: nil,
+				NewContent: newEntry.content,
+			})
+			continue
+		}
+
+		// Skip unchanged directories and files.
+		if oldEntry.version.Mode.IsDir() && newEntry.version.Mode == oldEntry.version.Mode {
+			continue
+		}
+		if oldEntry.version == newEntry.version {
+			continue
+		}
+
+		changes = append(changes, FileChange{
+			Path:       p,
+			Kind:       ModifiedFileChange,
+			Old:        oldEntry.version,
+			New:        newEntry.version,
+			OldContent: oldEntry.content,
+			NewContent: newEntry.content,
+		})
+	}
+
+	// Old files missing in new layer were deleted.
+	reported := make(map[string]struct{})
+	for p, oldEntry := range oldEntries.files {
+		if _, exist := newEntries.files[p]; exist {
+			continue
+		}
+
+		changes = append(changes, FileChange{
+			Path:       p,
+			Kind:       DeletedFileChange,
+			Old:        oldEntry.version,
+			New:        deleted,
+			OldContent: oldEntry.content,
+			NewContent: nil,
+		})
+		reported[p] = struct{}{}
+	}
+
+	// Whiteouts without previous version (e.g. reverse diff unavailable).
+	for _, p := range newEntries.whiteouts {
+		if _, isReported := reported[p]; isReported {
+			continue
+		}
+		if _, exist := newEntries.files[p]; exist {
+			continue
+		}
+
+		changes = append(changes, FileChange{
+			Path:       p,
+			Kind:       DeletedFileChange,
+			Old:        FileVersion{},
+			New:        deleted,
+			OldContent: nil,
+			NewContent: nil,
+		})
+		reported[p] = struct{}{}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
@@ -0,0 +1,200 @@
+package libocitree
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileChanges(t *testing.T) {
+	// Diff from old to new rootfs
+	newLayer := newTestLayer(t, map[string]string{
+		"added":                 "added\n",
+		"modified":              "line 1\nline 2 modified\n",
+		"unchanged":             "unchanged\n",
+		".wh.deleted":           "",
+		"opaque/.wh..wh..opq":   "",
+		"opaque/kept":           "kept\n",
+		".wh.missing-from-diff": "",
+	})
+	// Reverse diff, from new to old rootfs
+	oldLayer := newTestLayer(t, map[string]string{
+		"modified":         "line 1\nline 2\n",
+		"unchanged":        "unchanged\n",
+		"deleted":          "deleted\n",
+		"opaque/kept":      "kept\n",
+		"opaque/deleted":   "deleted\n",
+		".wh.added":        "",
+		"opaque/.wh.added": "",
+	})
+
+	newEntries, err := readLayerEntries(bytes.NewReader(newLayer), true)
+	require.NoError(t, err)
+	oldEntries, err := readLayerEntries(bytes.NewReader(oldLayer), true)
+	require.NoError(t, err)
+
+	changes := fileChanges(oldEntries, newEntries)
+
+	actual := make([]string, len(changes))
+	for i, change := range changes {
+		actual[i] = change.Kind.Short() + " " + change.Path
+	}
+	require.Equal(t, []string{
+		"A /added",
+		"D /deleted",
+		"D /missing-from-diff",
+		"M /modified",
+		"D /opaque/deleted",
+	}, actual)
+
+	t.Run("UnifiedDiff", func(t *testing.T) {
+		diff, ok := changes[3].UnifiedDiff()
+		require.True(t, ok)
+		require.Equal(t, `--- a/modified
++++ b/modified
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
++line 2 modified
+`, diff)
+
+		diff, ok = changes[0].UnifiedDiff()
+		require.True(t, ok)
+		require.Equal(t, `--- /dev/null
++++ b/added
+@@ -0,0 +1 @@
++added
+`, diff)
+	})
+
+	t.Run("LineStats", func(t *testing.T) {
+		added, deleted, ok := changes[3].LineStats()
+		require.True(t, ok)
+		require.Equal(t, 1, added)
+		require.Equal(t, 1, deleted)
+
+		added, deleted, ok = changes[1].LineStats()
+		require.True(t, ok)
+		require.Equal(t, 0, added)
+		require.Equal(t, 1, deleted)
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		change := FileChange{
+			Path:       "/binary",
+			Kind:       AddedFileChange,
+			Old:        FileVersion{Deleted: true},
+			New:        FileVersion{Mode: 0644, Size: 3},
+			OldContent: nil,
+			NewContent: []byte{0, 1, 2},
+		}
+		require.True(t, change.IsBinary())
+
+		_, ok := change.UnifiedDiff()
+		require.False(t, ok)
+	})
+}
+
+func TestRepositoryDiff(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	err = repo.Exec(ExecOptions{
+		Stdin:        nil,
+		Stdout:       nil,
+		Stderr:       nil,
+		Message:      "",
+		ReportWriter: nil,
+	}, "/bin/sh", "-c", "echo added > /added && echo patched >> /etc/motd && rm -rf /etc/issue /etc/apk/keys")
+	require.NoError(t, err)
+
+	parentRef, err := manager.ResolveRelativeReference(reference.RelativeFromReferenceAndOffset(repo.HeadRef(), 1))
+	require.NoError(t, err)
+
+	t.Run("SameReference", func(t *testing.T) {
+		changes, err := repo.Diff(repo.HeadRef(), repo.HeadRef(), DiffOptions{})
+		require.NoError(t, err)
+		require.Len(t, changes, 0)
+	})
+
+	t.Run("ParentToHead", func(t *testing.T) {
+		changes, err := repo.Diff(parentRef, repo.HeadRef(), DiffOptions{Content: true})
+		require.NoError(t, err)
+
+		kinds := make(map[string]FileChangeKind)
+		for _, change := range changes {
+			kinds[change.Path] = change.Kind
+
+			if change.Path == "/etc/motd" {
+				diff, ok := change.UnifiedDiff()
+				require.True(t, ok)
+				require.Contains(t, diff, "+patched\n")
+			}
+		}
+
+		require.Equal(t, AddedFileChange, kinds["/added"])
+		require.Equal(t, ModifiedFileChange, kinds["/etc/motd"])
+		require.Equal(t, DeletedFileChange, kinds["/etc/issue"])
+		require.Equal(t, DeletedFileChange, kinds["/etc/apk/keys"])
+		// Unchanged directories aren't reported
+		require.NotContains(t, kinds, "/etc")
+	})
+
+	t.Run("HeadToParent", func(t *testing.T) {
+		changes, err := repo.Diff(repo.HeadRef(), parentRef, DiffOptions{})
+		require.NoError(t, err)
+
+		kinds := make(map[string]FileChangeKind)
+		for _, change := range changes {
+			kinds[change.Path] = change.Kind
+		}
+
+		require.Equal(t, DeletedFileChange, kinds["/added"])
+		require.Equal(t, ModifiedFileChange, kinds["/etc/motd"])
+		require.Equal(t, AddedFileChange, kinds["/etc/issue"])
+	})
+
+	t.Run("CommitChanges", func(t *testing.T) {
+		commit, err := repo.Commit(repo.HeadRef())
+		require.NoError(t, err)
+		require.Equal(t, repo.ID(), commit.ID())
+		require.Equal(t, parentRef.IdOrTag()[len(reference.IdPrefix):], commit.Parent().ID())
+
+		changes, err := repo.CommitChanges(commit, DiffOptions{})
+		require.NoError(t, err)
+
+		expectedChanges, err := repo.Diff(parentRef, repo.HeadRef(), DiffOptions{})
+		require.NoError(t, err)
+		require.Equal(t, expectedChanges, changes)
+	})
+
+	t.Run("CommitChangesWithoutParentImage", func(t *testing.T) {
+		// Parent of alpine image top commit has no image associated.
+		commit, err := repo.Commit(parentRef)
+		require.NoError(t, err)
+		require.False(t, hasImage(commit.Parent()))
+
+		// Alpine top commit (CMD) has an empty layer.
+		changes, err := repo.CommitChanges(commit, DiffOptions{})
+		require.NoError(t, err)
+		require.Len(t, changes, 0)
+	})
+}
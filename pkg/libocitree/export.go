@@ -0,0 +1,224 @@
+package libocitree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/containers/common/libimage"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnknownExportFormat = errors.New("unknown export format")
+)
+
+// ExportFormat define the format of an exported image.
+type ExportFormat uint
+
+const (
+	// RootfsTarExportFormat exports the flattened rootfs as a tar archive.
+	RootfsTarExportFormat ExportFormat = iota
+	// OCIExportFormat exports the image, its history and its tags as an OCI
+	// image layout directory.
+	OCIExportFormat
+	// DockerArchiveExportFormat exports the image as an archive that can be
+	// loaded using `docker load`.
+	DockerArchiveExportFormat
+	UnknownExportFormat
+)
+
+// String implements fmt.Stringer.
+func (ef ExportFormat) String() string {
+	switch ef {
+	case RootfsTarExportFormat:
+		return "rootfs-tar"
+	case OCIExportFormat:
+		return "oci"
+	case DockerArchiveExportFormat:
+		return "docker-archive"
+	default:
+		return "unknown"
+	}
+}
+
+// ExportFormatFromString parses the given export format.
+func ExportFormatFromString(str string) ExportFormat {
+	switch strings.ToLower(str) {
+	case "rootfs-tar", "":
+		return RootfsTarExportFormat
+	case "oci":
+		return OCIExportFormat
+	case "docker-archive":
+		return DockerArchiveExportFormat
+	default:
+		return UnknownExportFormat
+	}
+}
+
+// ExportOptions holds options for Manager.Export method.
+type ExportOptions struct {
+	Format ExportFormat
+	// Output is the path of the exported archive or directory.
+	Output string
+
+	ReportWriter io.Writer
+}
+
+// Export writes the image with the given reference to the output path using
+// the format specified in options.
+func (m *Manager) Export(ref reference.Reference, options ExportOptions) error {
+	img, err := m.lookupImage(ref)
+	if err != nil {
+		return err
+	}
+
+	switch options.Format {
+	case RootfsTarExportFormat:
+		return m.exportRootfsTar(ref, options)
+
+	case OCIExportFormat:
+		tags, err := repositoryTags(img, ref.Name())
+		if err != nil {
+			return err
+		}
+
+		// Untagged image
+		if len(tags) == 0 {
+			return m.save([]string{img.ID()}, "oci-dir", nil, options)
+		}
+
+		// Every tag is added to the same layout
+		for _, tag := range tags {
+			err = m.save([]string{tag}, "oci-dir", nil, options)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case DockerArchiveExportFormat:
+		tags, err := repositoryTags(img, ref.Name())
+		if err != nil {
+			return err
+		}
+
+		return m.save([]string{img.ID()}, "docker-archive", tags, options)
+
+	default:
+		return ErrUnknownExportFormat
+	}
+}
+
+// exportRootfsTar writes flattened rootfs of image with the given reference
+// as a tar archive.
+func (m *Manager) exportRootfsTar(ref reference.Reference, options ExportOptions) error {
+	builder, err := m.repoBuilder(ref, options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	mountpoint, err := builder.Mount("")
+	if err != nil {
+		return fmt.Errorf("failed to mount builder container: %w", err)
+	}
+	defer builder.Unmount()
+
+	rootfs, err := archive.Tar(mountpoint, archive.Uncompressed)
+	if err != nil {
+		return fmt.Errorf("failed to archive rootfs: %w", err)
+	}
+	defer rootfs.Close()
+
+	f, err := os.Create(options.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, rootfs)
+	if err != nil {
+		return fmt.Errorf("failed to write rootfs archive: %w", err)
+	}
+
+	return f.Close()
+}
+
+// repositoryTags returns names of the given image that are part of the
+// repository with the given name. Reserved tags such as HEAD are filtered.
+func repositoryTags(img *libimage.Image, name reference.Name) ([]string, error) {
+	namedTags, err := img.NamedTaggedRepoTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve image tags: %w", err)
+	}
+
+	tags := make([]string, 0, len(namedTags))
+	for _, namedTag := range namedTags {
+		// Filter reserved tags
+		remoteRef, err := reference.RemoteRefFromString(namedTag.String())
+		if err != nil {
+			logrus.Debugf("skipping %q because of error: %v", namedTag, err)
+			continue
+		}
+
+		if remoteRef.Name() == name {
+			tags = append(tags, remoteRef.String())
+		}
+	}
+
+	return tags, nil
+}
+
+// save saves the given images using libimage.
+func (m *Manager) save(names []string, format string, additionalTags []string, options ExportOptions) error {
+	err := m.rt.Save(context.Background(), names, format, options.Output, &libimage.SaveOptions{
+		CopyOptions: libimage.CopyOptions{
+			SystemContext:                    m.rt.SystemContext(),
+			SourceLookupReferenceFunc:        nil,
+			DestinationLookupReferenceFunc:   nil,
+			CompressionFormat:                nil,
+			CompressionLevel:                 nil,
+			AuthFilePath:                     "",
+			BlobInfoCacheDirPath:             "",
+			CertDirPath:                      "",
+			DirForceCompress:                 false,
+			InsecureSkipTLSVerify:            0,
+			MaxRetries:                       nil,
+			RetryDelay:                       nil,
+			ManifestMIMEType:                 "",
+			OciAcceptUncompressedLayers:      false,
+			OciEncryptConfig:                 nil,
+			OciEncryptLayers:                 nil,
+			OciDecryptConfig:                 nil,
+			Progress:                         nil,
+			PolicyAllowStorage:               true,
+			SignaturePolicyPath:              "",
+			SignBy:                           "",
+			SignPassphrase:                   "",
+			SignBySigstorePrivateKeyFile:     "",
+			SignSigstorePrivateKeyPassphrase: nil,
+			RemoveSignatures:                 false,
+			Writer:                           options.ReportWriter,
+			Architecture:                     "",
+			OS:                               "",
+			Variant:                          "",
+			Username:                         "",
+			Password:                         "",
+			Credentials:                      "",
+			IdentityToken:                    "",
+		},
+		AdditionalTags: additionalTags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export %v as %v: %w", names, format, err)
+	}
+
+	return nil
+}
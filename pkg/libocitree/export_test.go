@@ -0,0 +1,123 @@
+package libocitree
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerExport(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	name, err := reference.NameFromString("localhost/ocitree/export")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello"), []byte("world\n"), 0644))
+
+	err = manager.Init(name, InitOptions{
+		FromDir:      dir,
+		FromTar:      "",
+		Message:      "",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(name)
+	require.NoError(t, err)
+
+	tag, err := reference.RemoteTagFromString("v1")
+	require.NoError(t, err)
+	require.NoError(t, repo.AddTag(tag))
+
+	// tarEntries returns content of regular files of the given tar archive.
+	tarEntries := func(path string) map[string]string {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		entries := make(map[string]string)
+		tr := tar.NewReader(f)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+
+			if hdr.Typeflag == tar.TypeReg {
+				content, err := io.ReadAll(tr)
+				require.NoError(t, err)
+				entries[filepath.Clean(hdr.Name)] = string(content)
+			}
+		}
+
+		return entries
+	}
+
+	t.Run("RootfsTar", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "rootfs.tar")
+		err := manager.Export(repo.HeadRef(), ExportOptions{
+			Format:       RootfsTarExportFormat,
+			Output:       output,
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "world\n", tarEntries(output)["hello"])
+	})
+
+	t.Run("OCI", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "layout")
+		err := manager.Export(repo.HeadRef(), ExportOptions{
+			Format:       OCIExportFormat,
+			Output:       output,
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		rawIndex, err := os.ReadFile(filepath.Join(output, "index.json"))
+		require.NoError(t, err)
+
+		index := struct {
+			Manifests []struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"manifests"`
+		}{}
+		require.NoError(t, json.Unmarshal(rawIndex, &index))
+
+		refNames := make([]string, 0)
+		for _, manifest := range index.Manifests {
+			refNames = append(refNames, manifest.Annotations["org.opencontainers.image.ref.name"])
+		}
+		require.ElementsMatch(t, []string{
+			"localhost/ocitree/export:v1",
+		}, refNames)
+	})
+
+	t.Run("DockerArchive", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "image.tar")
+		err := manager.Export(repo.HeadRef(), ExportOptions{
+			Format:       DockerArchiveExportFormat,
+			Output:       output,
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		manifest := []struct {
+			RepoTags []string `json:"RepoTags"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(tarEntries(output)["manifest.json"]), &manifest))
+		require.Len(t, manifest, 1)
+		require.ElementsMatch(t, []string{
+			"localhost/ocitree/export:v1",
+		}, manifest[0].RepoTags)
+	})
+}
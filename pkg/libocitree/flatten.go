@@ -0,0 +1,221 @@
+package libocitree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containers/buildah"
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+var (
+	ErrUnknownFlattenMode = errors.New("unknown flatten mode")
+	ErrNothingToFlatten   = errors.New("nothing to flatten, HEAD has no ocitree commits")
+)
+
+// FlattenMode define which commits are flattened.
+type FlattenMode uint
+
+const (
+	// CommitsFlattenMode flattens ocitree commits on top of the non-ocitree
+	// base image into a single commit. Base image layers are kept.
+	CommitsFlattenMode FlattenMode = iota
+	// ImageFlattenMode flattens the whole image into a single layer.
+	ImageFlattenMode
+	UnknownFlattenMode
+)
+
+// String implements fmt.Stringer.
+func (fm FlattenMode) String() string {
+	switch fm {
+	case CommitsFlattenMode:
+		return "commits"
+	case ImageFlattenMode:
+		return "image"
+	default:
+		return "unknown"
+	}
+}
+
+// FlattenModeFromString parses the given flatten mode.
+func FlattenModeFromString(str string) FlattenMode {
+	switch strings.ToLower(str) {
+	case "commits", "":
+		return CommitsFlattenMode
+	case "image":
+		return ImageFlattenMode
+	default:
+		return UnknownFlattenMode
+	}
+}
+
+// FlattenOptions holds options for Repository.Flatten method.
+type FlattenOptions struct {
+	Mode FlattenMode
+	// Tag is the tag of the flattened image. If nil, flattened image is
+	// committed as the new HEAD.
+	Tag reference.Tag
+	// Message is the message of the flattened commit. It defaults to
+	// "Flatten <n> commits". A summary of flattened commits is always
+	// appended.
+	Message string
+
+	ReportWriter io.Writer
+}
+
+// Flatten collapses commits of HEAD into a single commit and returns the
+// flattened image ID. If no tag is specified in options, HEAD is moved to the
+// flattened image.
+func (r *Repository) Flatten(options FlattenOptions) (string, error) {
+	if options.Mode == UnknownFlattenMode {
+		return "", ErrUnknownFlattenMode
+	}
+	if r.RebaseInProgress() {
+		return "", ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return "", err
+	}
+
+	commits, err := r.Commits()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve repository commits: %w", err)
+	}
+
+	var builder *buildah.Builder
+	flattened := commits
+	squash := true
+
+	switch options.Mode {
+	case CommitsFlattenMode:
+		baseIndex := 0
+		for baseIndex < len(commits) && commits[baseIndex].WasCreatedByOcitree() {
+			baseIndex++
+		}
+		if baseIndex == 0 {
+			return "", ErrNothingToFlatten
+		}
+		flattened = commits[:baseIndex]
+
+		// Repository has no base image, flatten it completely
+		if baseIndex == len(commits) {
+			builder, err = r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+			break
+		}
+
+		squash = false
+		builder, err = r.baseBuilder(&commits[baseIndex], flattened, options.ReportWriter)
+
+	case ImageFlattenMode:
+		builder, err = r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer builder.Delete()
+
+	commitOptions := CommitOptions{
+		CreatedBy:    FlattenCommitOperation.String() + " " + options.Mode.String(),
+		Message:      flattenMessage(options.Message, flattened),
+		Squash:       squash,
+		Record:       nil,
+		ReportWriter: options.ReportWriter,
+	}
+
+	// Commit on HEAD
+	if options.Tag == nil {
+		err = r.commit(builder, FlattenReflogOperation, commitOptions)
+		if err != nil {
+			return "", err
+		}
+
+		return r.ID(), nil
+	}
+
+	// Commit on tag
+	tagRef := reference.NewLocal(r.Name(), reference.LocalTagFromTag(options.Tag))
+	err = commit(builder, commitOptions, r.runtime.storageReference(tagRef), r.runtime.systemContext())
+	if err != nil {
+		return "", err
+	}
+
+	img, err := r.runtime.lookupImage(tagRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to lookup flattened image: %w", err)
+	}
+
+	return img.ID(), nil
+}
+
+// baseBuilder returns a builder based on the given base commit containing
+// changes made on top of it by HEAD. Configuration changes of the given
+// commits made on top of base are replayed.
+func (r *Repository) baseBuilder(base *Commit, commits Commits, reportWriter io.Writer) (*buildah.Builder, error) {
+	if !hasImage(base) {
+		return nil, fmt.Errorf("failed to retrieve base commit: %w", ErrCommitHasNoImageAssociated)
+	}
+
+	baseID, err := reference.IDFromString(base.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base commit ID: %w", err)
+	}
+
+	layer, err := readDiff(r.runtime.diff(base.ID(), r.ID()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute changes made on top of base commit: %w", err)
+	}
+
+	builder, err := r.runtime.repoBuilder(reference.NewLocal(r.Name(), baseID), reportWriter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyLayers(builder, layer)
+	if err != nil {
+		builder.Delete()
+		return nil, err
+	}
+
+	// From older to newer commit
+	for i := len(commits) - 1; i >= 0; i-- {
+		err = replayConfig(builder, commits[i].CreatedBy())
+		if err != nil {
+			builder.Delete()
+			return nil, fmt.Errorf("failed to replay configuration changes of commit %v: %w", commits[i].ID(), err)
+		}
+	}
+
+	return builder, nil
+}
+
+// flattenMessage returns the message of a flatten commit followed by a
+// summary of flattened commits, from older to newer.
+func flattenMessage(message string, flattened Commits) string {
+	if message == "" {
+		message = fmt.Sprintf("Flatten %d commits", len(flattened))
+	}
+
+	summary := strings.Builder{}
+	summary.WriteString(strings.TrimRight(message, "\n"))
+	summary.WriteString("\n")
+
+	for i := len(flattened) - 1; i >= 0; i-- {
+		commit := &flattened[i]
+
+		line := messageSubject(commit.Message())
+		if line == "" {
+			line = commit.CreatedBy()
+			if commit.WasCreatedByOcitree() {
+				line = line[len(CommitPrefix):]
+			}
+		}
+
+		summary.WriteString("\n* ")
+		summary.WriteString(line)
+	}
+
+	return summary.String()
+}
@@ -0,0 +1,112 @@
+package libocitree
+
+import (
+	"testing"
+
+	"github.com/containers/common/libimage"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenMessage(t *testing.T) {
+	commits := Commits{
+		newTestCommit(CommitPrefix+`EXEC ["touch" "/b"]`, "second commit\n\nbody"),
+		newTestCommit(CommitPrefix+`EXEC ["touch" "/a"]`, ""),
+	}
+
+	require.Equal(t, `Flatten 2 commits
+
+* EXEC ["touch" "/a"]
+* second commit`, flattenMessage("", commits))
+
+	require.Equal(t, `Release
+
+* EXEC ["touch" "/a"]
+* second commit`, flattenMessage("Release\n", commits))
+}
+
+func TestRepositoryFlatten(t *testing.T) {
+	_, cleanup, repo, resetRef := setupResetTest(t)
+	defer cleanup()
+
+	origHead := repo.ID()
+	baseID := resetRef.IdOrTag()[len(reference.IdPrefix):]
+
+	t.Run("CommitsOnTag", func(t *testing.T) {
+		tag, err := reference.RemoteTagFromString("flat")
+		require.NoError(t, err)
+
+		id, err := repo.Flatten(FlattenOptions{
+			Mode:         CommitsFlattenMode,
+			Tag:          tag,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+		require.Equal(t, origHead, repo.ID(), "HEAD moved")
+
+		commit, err := repo.Commit(reference.NewLocal(repo.Name(), reference.LocalTagFromTag(tag)))
+		require.NoError(t, err)
+		require.Equal(t, id, commit.ID())
+		require.Equal(t, FlattenCommitOperation, commit.Operation())
+		require.Equal(t, "Flatten 3 commits\n\n* commit 1\n* commit 2\n* commit 3", commit.Message())
+		require.Equal(t, baseID, commit.Parent().ID())
+	})
+
+	t.Run("Image", func(t *testing.T) {
+		_, err := repo.Flatten(FlattenOptions{
+			Mode:         ImageFlattenMode,
+			Tag:          nil,
+			Message:      "flattened",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, origHead, repo.ID())
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Len(t, commits, 1)
+		require.Equal(t, FlattenCommitOperation, commits[0].Operation())
+		require.Contains(t, commits[0].Message(), "flattened\n\n")
+		require.Contains(t, commits[0].Message(), "* commit 3")
+
+		requireWorkingContainerFiles(t, repo, []string{"commit1", "commit2", "commit3", "etc/alpine-release"}, nil)
+
+		entries, err := repo.Reflog()
+		require.NoError(t, err)
+		require.Equal(t, FlattenReflogOperation, entries[0].Operation)
+	})
+
+	t.Run("NothingToFlatten", func(t *testing.T) {
+		_, err := repo.Flatten(FlattenOptions{
+			Mode:         CommitsFlattenMode,
+			Tag:          nil,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		// HEAD is a single ocitree commit without base
+		require.NoError(t, err)
+
+		err = repo.Checkout(resetRef)
+		require.NoError(t, err)
+
+		_, err = repo.Flatten(FlattenOptions{
+			Mode:         CommitsFlattenMode,
+			Tag:          nil,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrNothingToFlatten)
+	})
+}
+
+func newTestCommit(createdBy, message string) Commit {
+	return newCommit(libimage.ImageHistory{
+		ID:        "",
+		Created:   nil,
+		CreatedBy: createdBy,
+		Size:      0,
+		Comment:   message,
+		Tags:      nil,
+	})
+}
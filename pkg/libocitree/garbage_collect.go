@@ -0,0 +1,260 @@
+package libocitree
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	dockerref "github.com/containers/image/v5/docker/reference"
+	"github.com/containers/storage"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/sirupsen/logrus"
+)
+
+// GarbageCollectOptions holds options for Manager.GarbageCollect method.
+type GarbageCollectOptions struct {
+	// Repositories to garbage collect, all repositories are garbage collected
+	// if empty.
+	Repositories []reference.Name
+	// GracePeriod is the minimum age of collected images.
+	GracePeriod time.Duration
+	// DryRun reports unreachable images without removing them.
+	DryRun bool
+}
+
+// CollectedImage define an image removed by garbage collection.
+type CollectedImage struct {
+	ID string
+	// Names are the names previously held by the image.
+	Names   []string
+	Created time.Time
+}
+
+// GarbageCollectReport holds the result of a garbage collection.
+type GarbageCollectReport struct {
+	Images []CollectedImage
+	// ReclaimedSize is the size of layers used only by collected images.
+	ReclaimedSize int64
+}
+
+// GarbageCollect removes images that were part of a repository but aren't
+// reachable from its HEAD, a tag or its reflog anymore. Layers used only by
+// those images are removed too. Images used by a container are never
+// removed.
+func (m *Manager) GarbageCollect(options GarbageCollectOptions) (*GarbageCollectReport, error) {
+	names := make(map[string]struct{})
+	if len(options.Repositories) == 0 {
+		repos, err := m.Repositories()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories: %w", err)
+		}
+		for _, repo := range repos {
+			if repo != nil {
+				names[repo.Name().String()] = struct{}{}
+			}
+		}
+	} else {
+		for _, name := range options.Repositories {
+			names[name.String()] = struct{}{}
+		}
+	}
+
+	images, err := m.store.Images()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	containers, err := m.store.Containers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	layers, err := m.store.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list layers: %w", err)
+	}
+
+	// Named images, images used by containers and reflog entries are roots.
+	roots := make([]string, 0)
+	for _, img := range images {
+		if len(img.Names) > 0 {
+			roots = append(roots, img.ID)
+		}
+	}
+	for _, container := range containers {
+		if container.ImageID != "" {
+			roots = append(roots, container.ImageID)
+		}
+	}
+	for name := range names {
+		repoName, err := reference.NameFromString(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repository name: %w", err)
+		}
+
+		entries, err := readReflog(m, repoName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reflog of %v: %w", name, err)
+		}
+		for _, entry := range entries {
+			roots = append(roots, entry.NewID)
+			if entry.OldID != "" {
+				roots = append(roots, entry.OldID)
+			}
+		}
+	}
+
+	reachable, err := m.reachableImages(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find unreachable images of collected repositories
+	now := time.Now()
+	collected := make(map[string]struct{})
+	report := &GarbageCollectReport{
+		Images:        make([]CollectedImage, 0),
+		ReclaimedSize: 0,
+	}
+	for _, img := range images {
+		if _, isReachable := reachable[img.ID]; isReachable {
+			continue
+		}
+		if !imageBelongsTo(&img, names) {
+			continue
+		}
+		if now.Sub(img.Created) < options.GracePeriod {
+			continue
+		}
+
+		collected[img.ID] = struct{}{}
+		report.Images = append(report.Images, CollectedImage{
+			ID:      img.ID,
+			Names:   img.NamesHistory,
+			Created: img.Created,
+		})
+	}
+	sort.Slice(report.Images, func(i, j int) bool {
+		return report.Images[i].Created.Before(report.Images[j].Created)
+	})
+
+	report.ReclaimedSize = reclaimedSize(images, containers, layers, collected)
+
+	if options.DryRun {
+		return report, nil
+	}
+
+	for _, img := range report.Images {
+		_, err := m.store.DeleteImage(img.ID, true)
+		if err != nil {
+			return report, fmt.Errorf("failed to remove image %v: %w", img.ID, err)
+		}
+	}
+
+	return report, nil
+}
+
+// reachableImages returns IDs of the given images and their ancestors.
+func (m *Manager) reachableImages(roots []string) (map[string]struct{}, error) {
+	reachable := make(map[string]struct{})
+
+	for _, id := range roots {
+		if _, isReachable := reachable[id]; isReachable {
+			continue
+		}
+
+		img, _, err := m.rt.LookupImage(id, nil)
+		if err != nil {
+			// Reflog may reference removed images
+			logrus.Debugf("skipping garbage collection root %v: %v", id, err)
+			continue
+		}
+
+		history, err := img.History(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve history of image %v: %w", id, err)
+		}
+
+		reachable[img.ID()] = struct{}{}
+		for _, h := range history {
+			if h.ID != "" && h.ID != "<missing>" {
+				reachable[h.ID] = struct{}{}
+			}
+		}
+	}
+
+	return reachable, nil
+}
+
+// imageBelongsTo returns true if the given image has or had a name of one of
+// the given repositories.
+func imageBelongsTo(img *storage.Image, names map[string]struct{}) bool {
+	imgNames := make([]string, 0, len(img.Names)+len(img.NamesHistory))
+	imgNames = append(imgNames, img.Names...)
+	imgNames = append(imgNames, img.NamesHistory...)
+
+	for _, name := range imgNames {
+		named, err := dockerref.ParseNormalizedNamed(name)
+		if err != nil {
+			continue
+		}
+		if _, ok := names[named.Name()]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reclaimedSize returns the size of layers used only by collected images.
+func reclaimedSize(images []storage.Image, containers []storage.Container, layers []storage.Layer, collected map[string]struct{}) int64 {
+	layersByID := make(map[string]*storage.Layer, len(layers))
+	for i := range layers {
+		layersByID[layers[i].ID] = &layers[i]
+	}
+
+	walk := func(layerID string, visit func(*storage.Layer)) {
+		for layerID != "" {
+			layer, ok := layersByID[layerID]
+			if !ok {
+				return
+			}
+			visit(layer)
+			layerID = layer.Parent
+		}
+	}
+
+	// Layers used by kept images and containers
+	kept := make(map[string]struct{})
+	keep := func(layer *storage.Layer) { kept[layer.ID] = struct{}{} }
+	for _, img := range images {
+		if _, isCollected := collected[img.ID]; !isCollected {
+			walk(img.TopLayer, keep)
+		}
+	}
+	for _, container := range containers {
+		walk(container.LayerID, keep)
+	}
+
+	removed := make(map[string]struct{})
+	size := int64(0)
+	for _, img := range images {
+		if _, isCollected := collected[img.ID]; !isCollected {
+			continue
+		}
+
+		walk(img.TopLayer, func(layer *storage.Layer) {
+			if _, isKept := kept[layer.ID]; isKept {
+				return
+			}
+			if _, isRemoved := removed[layer.ID]; isRemoved {
+				return
+			}
+			removed[layer.ID] = struct{}{}
+			if layer.UncompressedSize > 0 {
+				size += layer.UncompressedSize
+			}
+		})
+	}
+
+	return size
+}
@@ -0,0 +1,89 @@
+package libocitree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerGarbageCollect(t *testing.T) {
+	manager, cleanup, repo, resetRef := setupResetTest(t)
+	defer cleanup()
+
+	origHead := repo.ID()
+
+	// Orphan a flattened image
+	tag, err := reference.RemoteTagFromString("flat")
+	require.NoError(t, err)
+	orphanID, err := repo.Flatten(FlattenOptions{
+		Mode:         CommitsFlattenMode,
+		Tag:          tag,
+		Message:      "",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+	tagRef := reference.NewLocal(repo.Name(), reference.LocalTagFromTag(tag))
+	err = manager.store.RemoveNames(orphanID, []string{tagRef.String()})
+	require.NoError(t, err)
+
+	// Original HEAD is only reachable from reflog
+	_, err = repo.Reset(resetRef, HardResetMode)
+	require.NoError(t, err)
+
+	imageExist := func(id string) bool {
+		refID, err := reference.IDFromString(id)
+		require.NoError(t, err)
+		_, err = manager.lookupImage(reference.NewLocal(repo.Name(), refID))
+		return err == nil
+	}
+
+	t.Run("GracePeriod", func(t *testing.T) {
+		report, err := manager.GarbageCollect(GarbageCollectOptions{
+			Repositories: nil,
+			GracePeriod:  time.Hour,
+			DryRun:       true,
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Images, 0)
+		require.Equal(t, int64(0), report.ReclaimedSize)
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		report, err := manager.GarbageCollect(GarbageCollectOptions{
+			Repositories: []reference.Name{repo.Name()},
+			GracePeriod:  0,
+			DryRun:       true,
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Images, 1)
+		require.Equal(t, orphanID, report.Images[0].ID)
+		require.Greater(t, report.ReclaimedSize, int64(0))
+		require.True(t, imageExist(orphanID))
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		report, err := manager.GarbageCollect(GarbageCollectOptions{
+			Repositories: []reference.Name{repo.Name()},
+			GracePeriod:  0,
+			DryRun:       false,
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Images, 1)
+
+		require.False(t, imageExist(orphanID))
+		require.True(t, imageExist(origHead))
+		require.True(t, imageExist(repo.ID()))
+
+		// Commits discarded by reset can still be recovered
+		relRef, err := reference.RelativeFromString(repo.Name().String() + "@{1}")
+		require.NoError(t, err)
+		ref, err := manager.ResolveRelativeReference(relRef)
+		require.NoError(t, err)
+
+		err = repo.Checkout(ref)
+		require.NoError(t, err)
+		require.Equal(t, origHead, repo.ID())
+	})
+}
@@ -0,0 +1,138 @@
+package libocitree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/storage/pkg/chrootarchive"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/opencontainers/go-digest"
+)
+
+var (
+	ErrImportDestNotDirectory = errors.New("import destination is not a directory")
+)
+
+// ImportOptions holds options for Repository.Import method.
+type ImportOptions struct {
+	// Dest is the directory where archive is applied, it defaults to "/".
+	Dest string
+	// Replace removes content of destination directory before applying
+	// the archive.
+	Replace bool
+	Message string
+
+	ReportWriter io.Writer
+}
+
+// Import applies the given archive as a layer on HEAD and commits it. Archive
+// may be compressed using gzip, bzip2, xz or zstd, decompression is done
+// in-process and doesn't require host binaries. Whiteout files contained in
+// the archive delete files in destination directory. Archive is applied
+// chrooted in destination directory so symbolic links can't escape it.
+func (r *Repository) Import(archivePath string, options ImportOptions) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
+	dest := filepath.Clean("/" + options.Dest)
+
+	archiveDigest, err := fileDigest(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to compute archive digest: %w", err)
+	}
+
+	builder, err := r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	mountpoint, err := builder.Mount("")
+	if err != nil {
+		return fmt.Errorf("failed to mount builder container: %w", err)
+	}
+	defer builder.Unmount()
+
+	// Resolve destination inside container rootfs
+	target, err := securejoin.SecureJoin(mountpoint, dest)
+	if err != nil {
+		return fmt.Errorf("failed to resolve import destination: %w", err)
+	}
+
+	if options.Replace {
+		err = removeDirContent(target)
+		if err != nil {
+			return fmt.Errorf("failed to remove content of %v: %w", dest, err)
+		}
+	}
+
+	err = os.MkdirAll(target, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create import destination: %w", err)
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return ErrImportDestNotDirectory
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	_, err = chrootarchive.ApplyLayer(target, f)
+	if err != nil {
+		return fmt.Errorf("failed to apply archive: %w", err)
+	}
+
+	createdBy := fmt.Sprintf("%v --replace=%v %v %v", ImportCommitOperation,
+		options.Replace, archiveDigest, dest)
+
+	return r.commit(builder, ImportReflogOperation, CommitOptions{
+		CreatedBy:    createdBy,
+		Message:      options.Message,
+		Squash:       false,
+		Record:       nil,
+		ReportWriter: options.ReportWriter,
+	})
+}
+
+// fileDigest returns the digest of the file at the given path.
+func fileDigest(path string) (digest.Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return digest.Canonical.FromReader(f)
+}
+
+// removeDirContent removes all entries of the given directory. Missing
+// directory is ignored.
+func removeDirContent(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package libocitree
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+func TestRepositoryImport(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	name, err := reference.NameFromString("localhost/ocitree/import")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "opt", "app"), 0755))
+	for _, file := range []string{"opt/app/old", "opt/app/kept", "opt/app/replaced"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(file), 0644))
+	}
+
+	err = manager.Init(name, InitOptions{
+		FromDir:      dir,
+		FromTar:      "",
+		Message:      "",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(name)
+	require.NoError(t, err)
+
+	writeCompressedArchive := func(compression archive.Compression, layer []byte) string {
+		buf := &bytes.Buffer{}
+		var w io.WriteCloser
+		var err error
+		if compression == archive.Xz {
+			w, err = xz.NewWriter(buf)
+		} else {
+			w, err = archive.CompressStream(buf, compression)
+		}
+		require.NoError(t, err)
+		_, err = w.Write(layer)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		path := filepath.Join(t.TempDir(), "layer"+compression.Extension())
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+		return path
+	}
+
+	writeArchive := func(files map[string]string) string {
+		return writeCompressedArchive(archive.Gzip, newTestLayer(t, files))
+	}
+
+	t.Run("Dest", func(t *testing.T) {
+		archivePath := writeArchive(map[string]string{
+			"new":      "new\n",
+			"replaced": "replaced\n",
+			".wh.old":  "",
+		})
+		rawArchive, err := os.ReadFile(archivePath)
+		require.NoError(t, err)
+
+		err = repo.Import(archivePath, ImportOptions{
+			Dest:         "/opt/app",
+			Replace:      false,
+			Message:      "import app",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, ImportCommitOperation, commits[0].Operation())
+		require.Contains(t, commits[0].CreatedBy(), digest.FromBytes(rawArchive).String())
+		require.Equal(t, "import app", commits[0].Message())
+
+		requireWorkingContainerFiles(t, repo,
+			[]string{"opt/app/new", "opt/app/kept", "opt/app/replaced"},
+			[]string{"opt/app/old", "new"})
+		require.Equal(t, "replaced\n", readRepoFile(t, repo, "/opt/app/replaced"))
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		archivePath := writeArchive(map[string]string{
+			"only": "only\n",
+		})
+
+		err = repo.Import(archivePath, ImportOptions{
+			Dest:         "/opt/app",
+			Replace:      true,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		requireWorkingContainerFiles(t, repo,
+			[]string{"opt/app/only"},
+			[]string{"opt/app/new", "opt/app/kept", "opt/app/replaced"})
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		for _, compression := range []archive.Compression{archive.Gzip, archive.Zstd, archive.Xz} {
+			name := compression.Extension()
+			archivePath := writeCompressedArchive(compression, newTestLayer(t, map[string]string{
+				name: name,
+			}))
+
+			err = repo.Import(archivePath, ImportOptions{
+				Dest:         "/opt/compressed",
+				Replace:      false,
+				Message:      "",
+				ReportWriter: nil,
+			})
+			require.NoError(t, err, name)
+			require.Equal(t, name, readRepoFile(t, repo, "/opt/compressed/"+name))
+		}
+	})
+
+	t.Run("SymlinkEscape", func(t *testing.T) {
+		hostDir := t.TempDir()
+		archivePath := writeCompressedArchive(archive.Uncompressed, newTestSymlinkEscapeLayer(t, hostDir))
+
+		_ = repo.Import(archivePath, ImportOptions{
+			Dest:         "/",
+			Replace:      false,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.NoFileExists(t, filepath.Join(hostDir, "escaped"))
+	})
+}
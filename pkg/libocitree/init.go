@@ -0,0 +1,108 @@
+package libocitree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/containers/storage/pkg/chrootarchive"
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+var (
+	ErrInitMultipleSources = errors.New("rootfs can't be imported from both a directory and an archive")
+)
+
+// InitOptions holds options for Manager.Init method.
+type InitOptions struct {
+	// FromDir is a directory containing the rootfs of the first commit.
+	FromDir string
+	// FromTar is a tar archive, possibly compressed, containing the rootfs
+	// of the first commit.
+	FromTar string
+	// Message is the message of the first commit. It defaults to
+	// "Initial commit".
+	Message string
+
+	ReportWriter io.Writer
+}
+
+// Init creates a new local repository with the given name. First commit of
+// the repository contains an empty rootfs or the rootfs imported from the
+// directory or the archive specified in options.
+func (m *Manager) Init(name reference.Name, options InitOptions) error {
+	if options.FromDir != "" && options.FromTar != "" {
+		return ErrInitMultipleSources
+	}
+
+	headRef := reference.NewLocal(name, reference.HeadTag)
+
+	// Ensure repository doesn't exist
+	if m.LocalRepositoryExist(name) {
+		return ErrLocalRepositoryAlreadyExist
+	}
+
+	builder, err := m.newBuilder("scratch", name.String(), options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	createdBy := InitCommitOperation.String()
+	if options.FromDir != "" || options.FromTar != "" {
+		mountpoint, err := builder.Mount("")
+		if err != nil {
+			return fmt.Errorf("failed to mount builder container: %w", err)
+		}
+		defer builder.Unmount()
+
+		// Archive is extracted chrooted in builder rootfs so symbolic links
+		// can't escape it.
+		archiver := chrootarchive.NewArchiver(nil)
+		if options.FromDir != "" {
+			createdBy += fmt.Sprintf(" --from-dir=%q", options.FromDir)
+			err = archiver.CopyWithTar(options.FromDir, mountpoint)
+		} else {
+			createdBy += fmt.Sprintf(" --from-tar=%q", options.FromTar)
+			err = archiver.UntarPath(options.FromTar, mountpoint)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import rootfs: %w", err)
+		}
+	}
+
+	message := options.Message
+	if message == "" {
+		message = "Initial commit"
+	}
+
+	err = commit(builder, CommitOptions{
+		CreatedBy:    createdBy,
+		Message:      message,
+		Squash:       false,
+		Record:       nil,
+		ReportWriter: options.ReportWriter,
+	}, m.storageReference(headRef), m.systemContext())
+	if err != nil {
+		return err
+	}
+
+	img, err := m.lookupImage(headRef)
+	if err != nil {
+		return fmt.Errorf("failed to lookup repository HEAD: %w", err)
+	}
+
+	err = appendReflog(m, name, ReflogEntry{
+		OldID:     "",
+		NewID:     img.ID(),
+		Operation: InitReflogOperation,
+		Message:   messageSubject(message),
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update reflog: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package libocitree
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerInit(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	t.Run("Scratch", func(t *testing.T) {
+		name, err := reference.NameFromString("localhost/ocitree/scratch")
+		require.NoError(t, err)
+
+		err = manager.Init(name, InitOptions{
+			FromDir:      "",
+			FromTar:      "",
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		repo, err := manager.Repository(name)
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Len(t, commits, 1)
+		require.Equal(t, InitCommitOperation, commits[0].Operation())
+		require.Equal(t, "Initial commit", commits[0].Message())
+		require.Nil(t, commits[0].Parent())
+
+		entries, err := repo.Reflog()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, InitReflogOperation, entries[0].Operation)
+		require.Equal(t, repo.ID(), entries[0].NewID)
+
+		err = manager.Init(name, InitOptions{
+			FromDir:      "",
+			FromTar:      "",
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrLocalRepositoryAlreadyExist)
+	})
+
+	t.Run("FromDir", func(t *testing.T) {
+		name, err := reference.NameFromString("localhost/ocitree/from-dir")
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "etc"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "etc", "hostname"), []byte("ocitree\n"), 0644))
+
+		err = manager.Init(name, InitOptions{
+			FromDir:      dir,
+			FromTar:      "",
+			Message:      "import rootfs",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		repo, err := manager.Repository(name)
+		require.NoError(t, err)
+		commit, err := repo.Commit(repo.HeadRef())
+		require.NoError(t, err)
+		require.Equal(t, "import rootfs", commit.Message())
+		require.Equal(t, "ocitree\n", readRepoFile(t, repo, "/etc/hostname"))
+	})
+
+	t.Run("FromTar", func(t *testing.T) {
+		name, err := reference.NameFromString("localhost/ocitree/from-tar")
+		require.NoError(t, err)
+
+		// Gzip compressed archive
+		buf := &bytes.Buffer{}
+		gw := gzip.NewWriter(buf)
+		_, err = gw.Write(newTestLayer(t, map[string]string{"hello": "world\n"}))
+		require.NoError(t, err)
+		require.NoError(t, gw.Close())
+
+		archivePath := filepath.Join(t.TempDir(), "rootfs.tar.gz")
+		require.NoError(t, os.WriteFile(archivePath, buf.Bytes(), 0644))
+
+		err = manager.Init(name, InitOptions{
+			FromDir:      "",
+			FromTar:      archivePath,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		repo, err := manager.Repository(name)
+		require.NoError(t, err)
+		require.Equal(t, "world\n", readRepoFile(t, repo, "/hello"))
+	})
+
+	t.Run("FromTarSymlinkEscape", func(t *testing.T) {
+		name, err := reference.NameFromString("localhost/ocitree/from-tar-symlink-escape")
+		require.NoError(t, err)
+
+		hostDir := t.TempDir()
+		archivePath := filepath.Join(t.TempDir(), "rootfs.tar")
+		require.NoError(t, os.WriteFile(archivePath, newTestSymlinkEscapeLayer(t, hostDir), 0644))
+
+		_ = manager.Init(name, InitOptions{
+			FromDir:      "",
+			FromTar:      archivePath,
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.NoFileExists(t, filepath.Join(hostDir, "escaped"))
+	})
+
+	t.Run("MultipleSources", func(t *testing.T) {
+		name, err := reference.NameFromString("localhost/ocitree/multiple-sources")
+		require.NoError(t, err)
+
+		err = manager.Init(name, InitOptions{
+			FromDir:      t.TempDir(),
+			FromTar:      "rootfs.tar",
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrInitMultipleSources)
+	})
+}
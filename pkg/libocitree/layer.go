@@ -0,0 +1,178 @@
+package libocitree
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/storage/pkg/archive"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/opencontainers/go-digest"
+)
+
+// FileVersion describes the version of a file in a rootfs.
+type FileVersion struct {
+	// Deleted is true if file doesn't exist in this version.
+	Deleted bool        `json:"deleted,omitempty"`
+	Mode    os.FileMode `json:"mode"`
+	Size    int64       `json:"size"`
+	// Digest is the digest of the file content, it is empty for
+	// non regular files.
+	Digest digest.Digest `json:"digest,omitempty"`
+}
+
+// String implements fmt.Stringer.
+func (fv FileVersion) String() string {
+	if fv.Deleted {
+		return "deleted"
+	}
+
+	if fv.Digest == "" {
+		return fv.Mode.String()
+	}
+
+	return fmt.Sprintf("%v %v bytes %v", fv.Mode, fv.Size, fv.Digest.Encoded()[:16])
+}
+
+// fileVersionFromPath returns the version of the file at the given path.
+func fileVersionFromPath(p string) (FileVersion, error) {
+	stat, err := os.Lstat(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return FileVersion{Deleted: true}, nil
+		}
+		return FileVersion{}, err
+	}
+
+	version := FileVersion{
+		Deleted: false,
+		Mode:    stat.Mode(),
+		Size:    stat.Size(),
+		Digest:  "",
+	}
+
+	if stat.Mode().IsRegular() {
+		f, err := os.Open(p)
+		if err != nil {
+			return FileVersion{}, err
+		}
+		defer f.Close()
+
+		version.Digest, err = digest.SHA256.FromReader(f)
+		if err != nil {
+			return FileVersion{}, err
+		}
+	}
+
+	return version, nil
+}
+
+// fileVersionFromTar returns the version of the file described by the given
+// tar header. Content of regular file is read from the tar reader.
+func fileVersionFromTar(hdr *tar.Header, r io.Reader) (FileVersion, error) {
+	if _, isWhiteout, _ := layerEntryPath(hdr); isWhiteout {
+		return FileVersion{Deleted: true}, nil
+	}
+
+	version := FileVersion{
+		Deleted: false,
+		Mode:    hdr.FileInfo().Mode(),
+		Size:    hdr.Size,
+		Digest:  "",
+	}
+
+	if hdr.Typeflag == tar.TypeReg {
+		var err error
+		version.Digest, err = digest.SHA256.FromReader(r)
+		if err != nil {
+			return FileVersion{}, err
+		}
+	}
+
+	return version, nil
+}
+
+// layerEntryPath returns the absolute rootfs path of the given layer tar
+// entry. Whiteout entries returns the path of the deleted file and opaque
+// whiteout entries the path of the opaque directory.
+func layerEntryPath(hdr *tar.Header) (p string, isWhiteout bool, isOpaque bool) {
+	p = path.Clean("/" + hdr.Name)
+	dir, base := path.Split(p)
+
+	if base == archive.WhiteoutOpaqueDir {
+		return path.Clean(dir), true, true
+	}
+
+	if strings.HasPrefix(base, archive.WhiteoutPrefix) {
+		return path.Join(dir, base[len(archive.WhiteoutPrefix):]), true, false
+	}
+
+	return p, false, false
+}
+
+// isPathUnder returns true if the given path is the given dir or is inside
+// the given dir.
+func isPathUnder(p, dir string) bool {
+	return p == dir || dir == "/" || strings.HasPrefix(p, dir+"/")
+}
+
+// filterLayer returns a copy of the given layer tar without entries for
+// which keep returns false.
+func filterLayer(layer []byte, keep func(hdr *tar.Header) bool) ([]byte, error) {
+	result := &bytes.Buffer{}
+	tw := tar.NewWriter(result)
+	tr := tar.NewReader(bytes.NewReader(layer))
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer: %w", err)
+		}
+
+		if !keep(hdr) {
+			continue
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write layer: %w", err)
+		}
+		_, err = io.Copy(tw, tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write layer: %w", err)
+		}
+	}
+
+	err := tw.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write layer: %w", err)
+	}
+
+	return result.Bytes(), nil
+}
+
+// rootfsPath returns the host path of the given rootfs path. Symbolic links
+// in parent directories are resolved inside the rootfs so the returned path
+// can't escape it, the last element is kept as is.
+func rootfsPath(mountpoint, p string) (string, error) {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return mountpoint, nil
+	}
+
+	dir, err := securejoin.SecureJoin(mountpoint, path.Dir(p))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, path.Base(p)), nil
+}
@@ -0,0 +1,179 @@
+package libocitree
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayerEntryPath(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		entry              string
+		expectedPath       string
+		expectedIsWhiteout bool
+		expectedIsOpaque   bool
+	}{
+		{
+			name:         "File",
+			entry:        "etc/hostname",
+			expectedPath: "/etc/hostname",
+		},
+		{
+			name:         "Directory",
+			entry:        "./etc/",
+			expectedPath: "/etc",
+		},
+		{
+			name:               "Whiteout",
+			entry:              "etc/.wh.hostname",
+			expectedPath:       "/etc/hostname",
+			expectedIsWhiteout: true,
+		},
+		{
+			name:               "OpaqueWhiteout",
+			entry:              "etc/.wh..wh..opq",
+			expectedPath:       "/etc",
+			expectedIsWhiteout: true,
+			expectedIsOpaque:   true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, isWhiteout, isOpaque := layerEntryPath(&tar.Header{Name: test.entry})
+			require.Equal(t, test.expectedPath, p)
+			require.Equal(t, test.expectedIsWhiteout, isWhiteout)
+			require.Equal(t, test.expectedIsOpaque, isOpaque)
+		})
+	}
+}
+
+func TestIsPathUnder(t *testing.T) {
+	require.True(t, isPathUnder("/etc", "/etc"))
+	require.True(t, isPathUnder("/etc/hostname", "/etc"))
+	require.True(t, isPathUnder("/etc/hostname", "/"))
+	require.False(t, isPathUnder("/etcetera", "/etc"))
+	require.False(t, isPathUnder("/", "/etc"))
+}
+
+func TestRootfsPath(t *testing.T) {
+	mountpoint := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(mountpoint, "usr"), 0755))
+	require.NoError(t, os.Symlink("/usr", filepath.Join(mountpoint, "lib")))
+	require.NoError(t, os.Symlink("/host/etc", filepath.Join(mountpoint, "etc")))
+	require.NoError(t, os.Symlink("../../..", filepath.Join(mountpoint, "up")))
+
+	for _, test := range []struct {
+		path     string
+		expected string
+	}{
+		{"/", mountpoint},
+		{"/usr/bin", filepath.Join(mountpoint, "usr/bin")},
+		// Parent directories are resolved inside rootfs
+		{"/lib/libc.so", filepath.Join(mountpoint, "usr/libc.so")},
+		{"/etc/passwd", filepath.Join(mountpoint, "host/etc/passwd")},
+		{"/up/usr/bin", filepath.Join(mountpoint, "usr/bin")},
+		{"/../../usr/bin", filepath.Join(mountpoint, "usr/bin")},
+		// Last element is kept as is
+		{"/lib", filepath.Join(mountpoint, "lib")},
+	} {
+		actual, err := rootfsPath(mountpoint, test.path)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, actual, test.path)
+	}
+}
+
+func TestFilterLayer(t *testing.T) {
+	layer := newTestLayer(t, map[string]string{
+		"a":          "a",
+		"dir/b":      "b",
+		"dir/.wh.c":  "",
+		"dir2/.wh.d": "",
+	})
+
+	filtered, err := filterLayer(layer, func(hdr *tar.Header) bool {
+		p, _, _ := layerEntryPath(hdr)
+		return !isPathUnder(p, "/dir")
+	})
+	require.NoError(t, err)
+
+	entries := readTestLayer(t, filtered)
+	require.Equal(t, map[string]string{
+		"a":          "a",
+		"dir2/.wh.d": "",
+	}, entries)
+}
+
+// newTestLayer returns a layer tar containing the given regular files.
+func newTestLayer(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		})
+		require.NoError(t, err)
+		_, err = tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+// readTestLayer returns the entries of the given layer tar and their content.
+func readTestLayer(t *testing.T, layer []byte) map[string]string {
+	entries := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(layer))
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries[hdr.Name] = string(content)
+	}
+
+	return entries
+}
+
+// newTestSymlinkEscapeLayer returns a layer containing an absolute symbolic
+// link to hostDir followed by a file written through it.
+func newTestSymlinkEscapeLayer(t *testing.T, hostDir string) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     "escape",
+		Linkname: hostDir,
+		Mode:     0777,
+	})
+	require.NoError(t, err)
+
+	content := []byte("escaped\n")
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "escape/escaped",
+		Mode:     0644,
+		Size:     int64(len(content)),
+	})
+	require.NoError(t, err)
+	_, err = tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
@@ -0,0 +1,81 @@
+package libocitree
+
+import (
+	"regexp"
+	"time"
+)
+
+// LogFilter define criteria commits must match to be part of a log. Zero
+// values don't filter commits.
+type LogFilter struct {
+	// MaxCount limits the number of commits if greater than zero.
+	MaxCount int
+	// Since excludes commits created before the given date.
+	Since *time.Time
+	// Until excludes commits created after the given date.
+	Until *time.Time
+	// Operations excludes commits whose operation isn't one of the given
+	// ones.
+	Operations []CommitOperation
+	// Grep excludes commits whose message doesn't match.
+	Grep *regexp.Regexp
+	// OcitreeOnly excludes commits not created by ocitree, such as base
+	// image history.
+	OcitreeOnly bool
+}
+
+// Match returns true if the given commit matches filter criteria, MaxCount
+// excepted.
+func (lf LogFilter) Match(commit *Commit) bool {
+	if lf.OcitreeOnly && !commit.WasCreatedByOcitree() {
+		return false
+	}
+
+	if lf.Since != nil || lf.Until != nil {
+		date := commit.CreationDate()
+		if date == nil {
+			return false
+		}
+		if lf.Since != nil && date.Before(*lf.Since) {
+			return false
+		}
+		if lf.Until != nil && date.After(*lf.Until) {
+			return false
+		}
+	}
+
+	if len(lf.Operations) > 0 {
+		found := false
+		for _, op := range lf.Operations {
+			if commit.Operation() == op {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if lf.Grep != nil && !lf.Grep.MatchString(commit.Message()) {
+		return false
+	}
+
+	return true
+}
+
+// Filter returns commits matching the given filter, in the same order.
+func (c Commits) Filter(filter LogFilter) Commits {
+	filtered := make(Commits, 0, len(c))
+	for i := range c {
+		if filter.MaxCount > 0 && len(filtered) >= filter.MaxCount {
+			break
+		}
+
+		if filter.Match(&c[i]) {
+			filtered = append(filtered, c[i])
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,79 @@
+package libocitree
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/containers/common/libimage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitsFilter(t *testing.T) {
+	date := func(day int) *time.Time {
+		d := time.Date(2022, time.November, day, 12, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	commits := newCommits([]libimage.ImageHistory{
+		{ID: "5", Created: date(5), CreatedBy: CommitPrefix + `EXEC ["apk" "add" "curl"]`, Comment: "install curl\n"},
+		{ID: "4", Created: date(4), CreatedBy: CommitPrefix + `ADD --chown="" --chmod="" ["/tmp/app"] /`, Comment: "add app\n"},
+		{ID: "3", Created: date(3), CreatedBy: CommitPrefix + `EXEC ["apk" "add" "git"]`, Comment: "install git\n"},
+		{ID: "2", Created: date(2), CreatedBy: `/bin/sh -c #(nop) CMD ["/bin/sh"]`, Comment: ""},
+		{ID: "1", Created: date(1), CreatedBy: `/bin/sh -c #(nop) ADD file:1234 in / `, Comment: ""},
+	})
+
+	ids := func(commits Commits) []string {
+		result := make([]string, len(commits))
+		for i := range commits {
+			result[i] = commits[i].ID()
+		}
+		return result
+	}
+
+	for _, test := range []struct {
+		name     string
+		filter   LogFilter
+		expected []string
+	}{
+		{
+			name:     "None",
+			filter:   LogFilter{},
+			expected: []string{"5", "4", "3", "2", "1"},
+		},
+		{
+			name:     "MaxCount",
+			filter:   LogFilter{MaxCount: 2},
+			expected: []string{"5", "4"},
+		},
+		{
+			name:     "SinceUntil",
+			filter:   LogFilter{Since: date(2), Until: date(4)},
+			expected: []string{"4", "3", "2"},
+		},
+		{
+			name:     "Operations",
+			filter:   LogFilter{Operations: []CommitOperation{ExecCommitOperation}},
+			expected: []string{"5", "3"},
+		},
+		{
+			name:     "Grep",
+			filter:   LogFilter{Grep: regexp.MustCompile(`^install`)},
+			expected: []string{"5", "3"},
+		},
+		{
+			name:     "OcitreeOnly",
+			filter:   LogFilter{OcitreeOnly: true},
+			expected: []string{"5", "4", "3"},
+		},
+		{
+			name:     "Combined",
+			filter:   LogFilter{MaxCount: 1, Operations: []CommitOperation{ExecCommitOperation}, Until: date(4)},
+			expected: []string{"3"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, ids(commits.Filter(test.filter)))
+		})
+	}
+}
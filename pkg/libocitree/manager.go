@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -14,6 +17,7 @@ import (
 	"github.com/containers/common/libimage"
 	"github.com/containers/common/pkg/config"
 	dockerref "github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/pkg/compression"
 	storageTransport "github.com/containers/image/v5/storage"
 	"github.com/containers/image/v5/types"
 	"github.com/containers/storage"
@@ -28,6 +32,7 @@ var (
 	ErrLocalRepositoryUnknown             = errors.New("unknown local repository")
 	ErrRelativeReferenceOffsetOutOfBounds = errors.New("relative reference offset is out of bounds")
 	ErrCommitHasNoImageAssociated         = errors.New("commit has no image associated")
+	ErrPushReferenceIsNotTagged           = errors.New("push reference must be tagged")
 )
 
 // Manager defines a repositories manager.
@@ -67,28 +72,65 @@ func (m *Manager) listImages(filters ...string) ([]*libimage.Image, error) {
 	})
 }
 
-func (m *Manager) diff(from, to *Commit) (io.ReadCloser, error) {
-	img, err := m.store.Image(to.ID())
+// diff implements imageRuntime. An empty fromImageID returns the whole top
+// layer of the given image.
+func (m *Manager) diff(fromImageID, toImageID string) (io.ReadCloser, error) {
+	img, err := m.store.Image(toImageID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve image associated to commit %v: %w", to.ID(), err)
+		return nil, fmt.Errorf("failed to retrieve image %v: %w", toImageID, err)
 	}
 
-	parentImg, err := m.store.Image(from.ID())
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve image associated to commit %v: %w", from.ID(), err)
+	fromLayer := ""
+	if fromImageID != "" {
+		parentImg, err := m.store.Image(fromImageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve image %v: %w", fromImageID, err)
+		}
+		fromLayer = parentImg.TopLayer
 	}
 
 	compression := archive.Uncompressed
-	diff, err := m.store.Diff(parentImg.TopLayer, img.TopLayer, &storage.DiffOptions{
+	diff, err := m.store.Diff(fromLayer, img.TopLayer, &storage.DiffOptions{
 		Compression: &compression,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute diff between layer %v and %v: %w", parentImg.TopLayer, img.TopLayer, err)
+		return nil, fmt.Errorf("failed to compute diff between layer %v and %v: %w", fromLayer, img.TopLayer, err)
 	}
 
 	return diff, nil
 }
 
+// repositoryDir implements imageRuntime
+func (m *Manager) repositoryDir(name reference.Name) (string, error) {
+	dir := filepath.Join(m.store.GraphRoot(), "ocitree", url.PathEscape(name.String()))
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("failed to create repository directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+// changes implements imageRuntime
+func (m *Manager) changes(fromImageID, toImageID string) ([]archive.Change, error) {
+	from, err := m.store.Image(fromImageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve image %v: %w", fromImageID, err)
+	}
+
+	to, err := m.store.Image(toImageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve image %v: %w", toImageID, err)
+	}
+
+	changes, err := m.store.Changes(from.TopLayer, to.TopLayer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute changes between layer %v and %v: %w", from.TopLayer, to.TopLayer, err)
+	}
+
+	return changes, nil
+}
+
 // lookupImage returns the image associated to the given ref.
 func (m *Manager) lookupImage(ref reference.Reference) (*libimage.Image, error) {
 	// Reference with digest/id.
@@ -150,7 +192,28 @@ func (m *Manager) LocalRepositoryExist(name reference.Name) bool {
 
 // ResolveRelativeReference turns a relative reference into an absolute one.
 func (m *Manager) ResolveRelativeReference(ref reference.Relative) (reference.Reference, error) {
-	img, err := m.lookupImage(ref.Base())
+	base := ref.Base()
+
+	// Replace base with HEAD position selected in reflog
+	if selector := ref.Reflog(); selector != nil {
+		entries, err := readReflog(m, base.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		rawID, err := resolveReflogSelector(entries, selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve reflog selector %q: %w", selector, err)
+		}
+
+		id, err := reference.IDFromString(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reflog entry ID: %w", err)
+		}
+		base = reference.NewLocal(base.Name(), id)
+	}
+
+	img, err := m.lookupImage(base)
 	if err != nil {
 		return nil, fmt.Errorf("failed to lookup base reference: %w", err)
 	}
@@ -172,7 +235,7 @@ func (m *Manager) ResolveRelativeReference(ref reference.Relative) (reference.Re
 		return nil, fmt.Errorf("failed to parse commit ID: %w", err)
 	}
 
-	return reference.NewLocal(ref.Base().Name(), id), nil
+	return reference.NewLocal(base.Name(), id), nil
 }
 
 // Repositories returns the list of repositories
@@ -223,6 +286,17 @@ func (m *Manager) Clone(remoteRef reference.RemoteRef, options CloneOptions) err
 		return fmt.Errorf("failed to add HEAD reference to image: %w", err)
 	}
 
+	err = appendReflog(m, headRef.Name(), ReflogEntry{
+		OldID:     "",
+		NewID:     img.ID(),
+		Operation: CloneReflogOperation,
+		Message:   remoteRef.String(),
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update reflog: %w", err)
+	}
+
 	return nil
 }
 
@@ -330,12 +404,248 @@ func (m *Manager) Fetch(remoteRef reference.RemoteRef, options FetchOptions) err
 	return pullErrs.ErrorOrNil()
 }
 
+// PushOptions holds configuration options for pushing operations.
+type PushOptions struct {
+	MaxRetries   uint
+	RetryDelay   time.Duration
+	ReportWriter io.Writer
+
+	// Compression defines the compression algorithm used for layers.
+	// Default compression of destination is used if nil.
+	Compression *compression.Algorithm
+	// AllTags pushes every remote tag of the repository instead of HEAD.
+	AllTags bool
+	// Destination overrides the registry destination. It must be prefixed
+	// with a transport (e.g. "oci:/path/to/layout") and is suffixed with
+	// the pushed tag.
+	Destination string
+}
+
+// Push pushes HEAD of the repository with the given name to the given remote
+// reference. If AllTags option is set, every remote tag of the repository is
+// pushed instead. Reserved local tags such as HEAD are never pushed.
+func (m *Manager) Push(remoteRef reference.RemoteRef, options PushOptions) error {
+	if !m.LocalRepositoryExist(remoteRef.Name()) {
+		return ErrLocalRepositoryUnknown
+	}
+
+	if options.AllTags {
+		return m.pushTags(remoteRef.Name(), &options)
+	}
+
+	if !strings.HasPrefix(remoteRef.IdOrTag(), reference.TagPrefix) {
+		return ErrPushReferenceIsNotTagged
+	}
+
+	head, err := m.lookupImage(reference.LocalFromName(remoteRef.Name()))
+	if err != nil {
+		return fmt.Errorf("failed to lookup repository HEAD: %w", err)
+	}
+
+	// Use image ID as source so HEAD tag doesn't leak (e.g. docker-archive
+	// destination preserve source name).
+	return m.pushRef(head.ID(), remoteRef, &options)
+}
+
+// pushTags pushes every remote tags of repository with the given name.
+func (m *Manager) pushTags(name reference.Name, options *PushOptions) error {
+	images, err := m.listImages("reference=" + name.String() + ":*")
+	if err != nil {
+		return fmt.Errorf("failed to list references to repository: %w", err)
+	}
+
+	var pushErrs *multierror.Error
+	for _, img := range images {
+		for _, imgName := range img.Names() {
+			// Filter reserved tags
+			imgRemoteRef, err := reference.RemoteRefFromString(imgName)
+			if err != nil {
+				logrus.Debugf("skipping %q because of error: %v", imgName, err)
+				continue
+			}
+
+			// Filter image name that don't match repository name
+			if imgRemoteRef.Name() != name {
+				continue
+			}
+
+			err = m.pushRef(imgRemoteRef.String(), imgRemoteRef, options)
+			if err != nil {
+				pushErrs = multierror.Append(pushErrs, err)
+			}
+		}
+	}
+
+	return pushErrs.ErrorOrNil()
+}
+
+func (m *Manager) pushRef(source string, ref reference.RemoteRef, options *PushOptions) error {
+	destination := "docker://" + ref.String()
+	if options.Destination != "" {
+		destination = options.Destination + ref.IdOrTag()
+	}
+
+	_, err := m.rt.Push(context.Background(), source, destination, &libimage.PushOptions{
+		CopyOptions: libimage.CopyOptions{
+			SystemContext:                    m.rt.SystemContext(),
+			SourceLookupReferenceFunc:        nil,
+			DestinationLookupReferenceFunc:   nil,
+			CompressionFormat:                options.Compression,
+			CompressionLevel:                 nil,
+			AuthFilePath:                     "",
+			BlobInfoCacheDirPath:             "",
+			CertDirPath:                      "",
+			DirForceCompress:                 false,
+			InsecureSkipTLSVerify:            0,
+			MaxRetries:                       &options.MaxRetries,
+			RetryDelay:                       &options.RetryDelay,
+			ManifestMIMEType:                 "",
+			OciAcceptUncompressedLayers:      false,
+			OciEncryptConfig:                 nil,
+			OciEncryptLayers:                 nil,
+			OciDecryptConfig:                 nil,
+			Progress:                         nil,
+			PolicyAllowStorage:               true,
+			SignaturePolicyPath:              "",
+			SignBy:                           "",
+			SignPassphrase:                   "",
+			SignBySigstorePrivateKeyFile:     "",
+			SignSigstorePrivateKeyPassphrase: nil,
+			RemoveSignatures:                 false,
+			Writer:                           options.ReportWriter,
+			Architecture:                     "",
+			OS:                               "",
+			Variant:                          "",
+			Username:                         "",
+			Password:                         "",
+			Credentials:                      "",
+			IdentityToken:                    "",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push %v to %v: %w", source, destination, err)
+	}
+
+	return nil
+}
+
 func (m *Manager) repoBuilder(ref reference.Reference, reportWriter io.Writer) (*buildah.Builder, error) {
+	return m.newBuilder(builderImage(ref), ref.Name().String(), reportWriter)
+}
+
+// workingContainerName returns the name of the working container of the
+// given repository.
+func workingContainerName(name reference.Name) string {
+	return name.String() + "-working-container"
+}
+
+// newWorkingContainer implements imageRuntime
+func (m *Manager) newWorkingContainer(ref reference.Reference) (*buildah.Builder, error) {
+	return m.newBuilder(builderImage(ref), workingContainerName(ref.Name()), nil)
+}
+
+// workingContainer implements imageRuntime
+func (m *Manager) workingContainer(name reference.Name) (*buildah.Builder, error) {
+	builder, err := buildah.OpenBuilder(m.store, workingContainerName(name))
+	if err != nil {
+		if errors.Is(err, storage.ErrContainerUnknown) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open working container: %w", err)
+	}
+
+	return builder, nil
+}
+
+// setWorkingContainer implements imageRuntime
+func (m *Manager) setWorkingContainer(name reference.Name, builder *buildah.Builder) error {
+	containerName := workingContainerName(name)
+	err := m.store.SetNames(builder.ContainerID, []string{containerName})
+	if err != nil {
+		return fmt.Errorf("failed to rename container %v: %w", builder.ContainerID, err)
+	}
+
+	builder.Container = containerName
+	err = builder.Save()
+	if err != nil {
+		return fmt.Errorf("failed to save working container: %w", err)
+	}
+
+	return nil
+}
+
+// containerChanges implements imageRuntime
+func (m *Manager) containerChanges(containerID string) ([]archive.Change, error) {
+	container, err := m.store.Container(containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve container %v: %w", containerID, err)
+	}
+
+	changes, err := m.store.Changes("", container.LayerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute changes of container %v: %w", containerID, err)
+	}
+
+	return changes, nil
+}
+
+// containerDiff implements imageRuntime
+func (m *Manager) containerDiff(containerID string) (io.ReadCloser, error) {
+	container, err := m.store.Container(containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve container %v: %w", containerID, err)
+	}
+
+	compression := archive.Uncompressed
+	diff, err := m.store.Diff("", container.LayerID, &storage.DiffOptions{
+		Compression: &compression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diff of container %v: %w", containerID, err)
+	}
+
+	return diff, nil
+}
+
+// mountImage implements imageRuntime
+func (m *Manager) mountImage(imageID string) (string, error) {
+	mountpoint, err := m.store.MountImage(imageID, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to mount image %v: %w", imageID, err)
+	}
+
+	return mountpoint, nil
+}
+
+// unmountImage implements imageRuntime
+func (m *Manager) unmountImage(imageID string) error {
+	_, err := m.store.UnmountImage(imageID, false)
+	if err != nil {
+		return fmt.Errorf("failed to unmount image %v: %w", imageID, err)
+	}
+
+	return nil
+}
+
+// builderImage returns the image name used to create a builder based on the
+// given reference. Image ID isn't a manifest digest so reference with an ID
+// is replaced by the bare ID.
+func builderImage(ref reference.Reference) string {
+	if strings.HasPrefix(ref.IdOrTag(), reference.IdPrefix) {
+		return ref.IdOrTag()[len(reference.IdPrefix):]
+	}
+
+	return ref.String()
+}
+
+// newBuilder returns a new builder container with the given name based on
+// fromImage. fromImage may be "scratch" to start from an empty rootfs.
+func (m *Manager) newBuilder(fromImage string, container string, reportWriter io.Writer) (*buildah.Builder, error) {
 	builder, err := buildah.NewBuilder(context.Background(), m.store, buildah.BuilderOptions{
 		Args:                  nil,
-		FromImage:             ref.String(),
+		FromImage:             fromImage,
 		ContainerSuffix:       "ocitree",
-		Container:             ref.Name().String(),
+		Container:             container,
 		PullPolicy:            buildah.PullNever,
 		Registry:              "",
 		BlobDirectory:         "",
@@ -3,8 +3,10 @@ package libocitree
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -305,6 +307,111 @@ func TestManagerResolveRelativeReference(t *testing.T) {
 	})
 }
 
+func TestManagerPush(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine:latest")
+	require.NoError(t, err)
+
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	// Add a tag to HEAD and start a rebase to add local REBASE_HEAD tag
+	testTag, err := reference.RemoteTagFromString("testtag")
+	require.NoError(t, err)
+	err = repo.AddTag(testTag)
+	require.NoError(t, err)
+	_, err = repo.RebaseSession(ref)
+	require.NoError(t, err)
+
+	t.Run("Head", func(t *testing.T) {
+		layoutDir := t.TempDir()
+		pushRef := reference.NewRemote(ref.Name(), testTag)
+
+		err := manager.Push(pushRef, PushOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+			Destination:  "oci:" + layoutDir,
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"testtag"}, ociLayoutRefNames(t, layoutDir))
+	})
+
+	t.Run("AllTags", func(t *testing.T) {
+		layoutDir := t.TempDir()
+
+		err := manager.Push(ref, PushOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+			AllTags:      true,
+			Destination:  "oci:" + layoutDir,
+		})
+		require.NoError(t, err)
+
+		refNames := ociLayoutRefNames(t, layoutDir)
+		require.ElementsMatch(t, []string{"latest", "testtag"}, refNames)
+		require.NotContains(t, refNames, reference.Head)
+		require.NotContains(t, refNames, reference.RebaseHead)
+	})
+
+	t.Run("IDReference", func(t *testing.T) {
+		id, err := reference.IDFromString(repo.ID())
+		require.NoError(t, err)
+
+		err = manager.Push(reference.NewRemote(ref.Name(), id), PushOptions{
+			Destination: "oci:" + t.TempDir(),
+		})
+		require.Error(t, err)
+		require.Equal(t, ErrPushReferenceIsNotTagged, err)
+	})
+
+	t.Run("UnknownRepository", func(t *testing.T) {
+		unknownRef, err := reference.RemoteRefFromString("archlinux")
+		require.NoError(t, err)
+
+		err = manager.Push(unknownRef, PushOptions{
+			Destination: "oci:" + t.TempDir(),
+		})
+		require.Error(t, err)
+		require.Equal(t, ErrLocalRepositoryUnknown, err)
+	})
+}
+
+// ociLayoutRefNames returns the reference names stored in index of the given
+// OCI layout directory.
+func ociLayoutRefNames(t *testing.T, layoutDir string) []string {
+	rawIndex, err := os.ReadFile(filepath.Join(layoutDir, "index.json"))
+	require.NoError(t, err)
+
+	index := struct {
+		Manifests []struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"manifests"`
+	}{}
+	err = json.Unmarshal(rawIndex, &index)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(index.Manifests))
+	for _, manifest := range index.Manifests {
+		names = append(names, manifest.Annotations["org.opencontainers.image.ref.name"])
+	}
+
+	return names
+}
+
 func newTestManager(t *testing.T) (manager *Manager, cleanup func()) {
 	store, systemContext, workdir := newStoreAndSystemContext(t)
 
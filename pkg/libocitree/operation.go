@@ -0,0 +1,206 @@
+package libocitree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	operationRecordVersion = 1
+	// operationRecordPrefix prefixes the history comment line holding the
+	// operation record of a commit.
+	operationRecordPrefix = "Ocitree-Operation: "
+)
+
+var (
+	ErrNoOperationRecord             = errors.New("commit has no operation record")
+	ErrUnknownOperationRecordVersion = errors.New("unknown operation record version")
+	ErrOperationMismatch             = errors.New("commit operation doesn't match")
+)
+
+// OperationRecord is a versioned and machine readable record of the operation
+// that created a commit and its arguments. It is stored in the history comment
+// of ocitree commits, after the commit message.
+type OperationRecord struct {
+	Version   int    `json:"version"`
+	Operation string `json:"operation"`
+	// Args holds the JSON encoded arguments of the operation. They are
+	// recorded for ADD, EXEC and CONFIG operations.
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// AddOperation holds arguments of an ADD operation.
+type AddOperation struct {
+	Sources []string `json:"sources"`
+	Dest    string   `json:"dest"`
+	Chown   string   `json:"chown,omitempty"`
+	Chmod   string   `json:"chmod,omitempty"`
+}
+
+// ExecOperation holds arguments of an EXEC operation.
+type ExecOperation struct {
+	Argv []string `json:"argv"`
+	Env  []string `json:"env,omitempty"`
+	User string   `json:"user,omitempty"`
+}
+
+// newOperationRecord returns a new record of the given operation and
+// arguments.
+func newOperationRecord(operation CommitOperation, args any) (*OperationRecord, error) {
+	record := &OperationRecord{
+		Version:   operationRecordVersion,
+		Operation: operation.String(),
+		Args:      nil,
+	}
+
+	if args != nil {
+		rawArgs, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize %v operation arguments: %w", operation, err)
+		}
+		record.Args = rawArgs
+	}
+
+	return record, nil
+}
+
+// newExecOperationRecord returns a new record of an EXEC operation running the
+// given command.
+func newExecOperationRecord(command []string) (*OperationRecord, error) {
+	return newOperationRecord(ExecCommitOperation, ExecOperation{
+		Argv: command,
+		Env:  nil,
+		User: execUser,
+	})
+}
+
+// operationRecordFromCreatedBy returns a record, without arguments, of the
+// operation of the given CreatedBy string.
+func operationRecordFromCreatedBy(createdBy string) *OperationRecord {
+	operation := strings.SplitN(strings.TrimPrefix(createdBy, CommitPrefix), " ", 2)[0]
+
+	return &OperationRecord{
+		Version:   operationRecordVersion,
+		Operation: operation,
+		Args:      nil,
+	}
+}
+
+// CommitOperation returns the recorded operation.
+func (or *OperationRecord) CommitOperation() CommitOperation {
+	return commitOperationFromString(or.Operation)
+}
+
+// decodeArgs decodes arguments of the given operation in v.
+func (or *OperationRecord) decodeArgs(operation CommitOperation, v any) error {
+	if or.CommitOperation() != operation {
+		return fmt.Errorf("%w: expected %v, got %v", ErrOperationMismatch, operation, or.Operation)
+	}
+	if or.Args == nil {
+		return fmt.Errorf("%v operation record has no arguments", operation)
+	}
+
+	err := json.Unmarshal(or.Args, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse %v operation arguments: %w", operation, err)
+	}
+
+	return nil
+}
+
+// historyComment returns the history comment of a commit with the given
+// message and operation record.
+func historyComment(message string, record *OperationRecord) (string, error) {
+	rawRecord, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize operation record: %w", err)
+	}
+
+	return message + "\n" + operationRecordPrefix + string(rawRecord) + "\n", nil
+}
+
+// splitHistoryComment splits the given history comment, stripped of base
+// image line, into a commit message and a raw operation record. Raw record is
+// empty if comment contains none.
+func splitHistoryComment(comment string) (string, string) {
+	i := strings.LastIndex("\n"+comment, "\n"+operationRecordPrefix)
+	if i == -1 {
+		return comment, ""
+	}
+
+	rawRecord := strings.SplitN(comment[i+len(operationRecordPrefix):], "\n", 2)[0]
+	message := ""
+	if i > 0 {
+		message = comment[:i-1]
+	}
+
+	return message, rawRecord
+}
+
+// OperationRecord returns the operation record of this commit.
+// ErrNoOperationRecord is returned for commits created before operation
+// records were introduced and for commits not created by ocitree.
+func (c *Commit) OperationRecord() (*OperationRecord, error) {
+	_, rawRecord := splitHistoryComment(c.comment())
+	if rawRecord == "" || !c.WasCreatedByOcitree() {
+		return nil, ErrNoOperationRecord
+	}
+
+	record := &OperationRecord{}
+	err := json.Unmarshal([]byte(rawRecord), record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse operation record: %w", err)
+	}
+	if record.Version != operationRecordVersion {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownOperationRecordVersion, record.Version)
+	}
+
+	return record, nil
+}
+
+// operationRecord returns the operation record of this commit or nil if it
+// has none or if it is invalid.
+func (c *Commit) operationRecord() *OperationRecord {
+	record, err := c.OperationRecord()
+	if err != nil {
+		return nil
+	}
+
+	return record
+}
+
+// AddOperation returns arguments of the ADD operation that created this
+// commit.
+func (c *Commit) AddOperation() (AddOperation, error) {
+	record, err := c.OperationRecord()
+	if err != nil {
+		return AddOperation{}, err
+	}
+
+	op := AddOperation{}
+	err = record.decodeArgs(AddCommitOperation, &op)
+	if err != nil {
+		return AddOperation{}, err
+	}
+
+	return op, nil
+}
+
+// ExecOperation returns arguments of the EXEC operation that created this
+// commit.
+func (c *Commit) ExecOperation() (ExecOperation, error) {
+	record, err := c.OperationRecord()
+	if err != nil {
+		return ExecOperation{}, err
+	}
+
+	op := ExecOperation{}
+	err = record.decodeArgs(ExecCommitOperation, &op)
+	if err != nil {
+		return ExecOperation{}, err
+	}
+
+	return op, nil
+}
@@ -0,0 +1,91 @@
+package libocitree
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitHistoryComment(t *testing.T) {
+	record, err := newExecOperationRecord([]string{"/bin/sh", "-c", "true"})
+	require.NoError(t, err)
+	expectedRecord, err := json.Marshal(record)
+	require.NoError(t, err)
+
+	for _, message := range []string{"", "subject", "subject\n\nbody\nOcitree-Operation in body"} {
+		gotMessage, rawRecord := splitHistoryComment(testHistoryComment(t, message, record))
+		require.Equal(t, message, gotMessage)
+		require.Equal(t, string(expectedRecord), rawRecord)
+	}
+
+	gotMessage, rawRecord := splitHistoryComment("legacy message\n")
+	require.Equal(t, "legacy message\n", gotMessage)
+	require.Equal(t, "", rawRecord)
+}
+
+func TestCommitOperationRecord(t *testing.T) {
+	t.Run("Exec", func(t *testing.T) {
+		argv := []string{"/bin/sh", "-c", `echo "a b" \ c`}
+		record, err := newExecOperationRecord(argv)
+		require.NoError(t, err)
+		commit := newTestCommit(CommitPrefix+`EXEC ["/bin/sh" "-c" "echo \"a b\" \ c"]`,
+			testHistoryComment(t, "commit", record)+"FROM alpine")
+
+		require.Equal(t, "commit", commit.Message())
+		require.Equal(t, ExecCommitOperation, commit.Operation())
+
+		execOp, err := commit.ExecOperation()
+		require.NoError(t, err)
+		require.Equal(t, argv, execOp.Argv)
+		require.Equal(t, execUser, execOp.User)
+
+		_, err = commit.AddOperation()
+		require.ErrorIs(t, err, ErrOperationMismatch)
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		addOp := AddOperation{
+			Sources: []string{"/tmp/a \"quoted\" file"},
+			Dest:    "/opt",
+			Chown:   "1000:1000",
+			Chmod:   "0644",
+		}
+		record, err := newOperationRecord(AddCommitOperation, addOp)
+		require.NoError(t, err)
+		commit := newTestCommit(CommitPrefix+"ADD ...", testHistoryComment(t, "", record))
+
+		require.Equal(t, "", commit.Message())
+
+		got, err := commit.AddOperation()
+		require.NoError(t, err)
+		require.Equal(t, addOp, got)
+	})
+
+	t.Run("NoRecord", func(t *testing.T) {
+		commit := newTestCommit(CommitPrefix+`EXEC ["true"]`, "commit\n")
+
+		require.Equal(t, ExecCommitOperation, commit.Operation())
+		_, err := commit.OperationRecord()
+		require.ErrorIs(t, err, ErrNoOperationRecord)
+		_, err = commit.ExecOperation()
+		require.ErrorIs(t, err, ErrNoOperationRecord)
+	})
+
+	t.Run("UnknownVersion", func(t *testing.T) {
+		commit := newTestCommit(CommitPrefix+`EXEC ["true"]`,
+			"commit\n"+operationRecordPrefix+`{"version":42,"operation":"EXEC"}`+"\n")
+
+		_, err := commit.OperationRecord()
+		require.ErrorIs(t, err, ErrUnknownOperationRecordVersion)
+	})
+}
+
+// testHistoryComment returns the history comment of a commit with the given
+// message and operation record.
+func testHistoryComment(t *testing.T, message string, record *OperationRecord) string {
+	comment, err := historyComment(message, record)
+	require.NoError(t, err)
+
+	return comment
+}
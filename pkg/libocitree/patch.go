@@ -0,0 +1,419 @@
+package libocitree
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+const (
+	patchVersion       = 1
+	patchExtension     = ".ocipatch"
+	patchHeaderEntry   = "patch.json"
+	patchLayerEntry    = "layer.tar"
+	patchSubjectMaxLen = 52
+)
+
+var (
+	ErrPatchRangeNotAncestor  = errors.New("range start is not an ancestor of range end")
+	ErrPatchNotOcitreeCommit  = errors.New("only commits created by ocitree can be formatted as patch")
+	ErrPatchInvalid           = errors.New("invalid patch file")
+	ErrPatchUnknownVersion    = errors.New("unknown patch version")
+	ErrNothingToFormatPatches = errors.New("no commits in range")
+)
+
+// Patch define a single commit exported as a standalone file.
+type Patch struct {
+	// ID is the ID of the formatted commit.
+	ID string
+	// ParentID is the ID of the parent of the formatted commit.
+	ParentID  string
+	CreatedBy string
+	Message   string
+	Date      time.Time
+	// Record is the operation record of the formatted commit, it is nil for
+	// commits without one.
+	Record *OperationRecord
+	// Layer is the uncompressed diff between parent and formatted commit.
+	Layer []byte
+	// ParentVersions holds versions in parent of paths touched by Layer so
+	// conflicts can be detected without parent image.
+	ParentVersions map[string]FileVersion
+}
+
+// patchHeader holds the persisted metadata of a Patch.
+type patchHeader struct {
+	Version        int                    `json:"version"`
+	ID             string                 `json:"id"`
+	ParentID       string                 `json:"parentId"`
+	ParentVersions map[string]FileVersion `json:"parentVersions"`
+	CreatedBy      string                 `json:"createdBy"`
+	Message        string                 `json:"message"`
+	Date           time.Time              `json:"date"`
+	Record         *OperationRecord       `json:"record,omitempty"`
+}
+
+// ReadPatch reads the patch file at the given path.
+func ReadPatch(path string) (*Patch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open patch file: %w", err)
+	}
+	defer f.Close()
+
+	var header *patchHeader
+	var layer []byte
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read patch file: %w", err)
+		}
+
+		switch hdr.Name {
+		case patchHeaderEntry:
+			header = &patchHeader{}
+			err = json.NewDecoder(tr).Decode(header)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse patch header: %w", err)
+			}
+
+		case patchLayerEntry:
+			layer, err = io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read patch layer: %w", err)
+			}
+		}
+	}
+
+	if header == nil || layer == nil {
+		return nil, ErrPatchInvalid
+	}
+	if header.Version != patchVersion {
+		return nil, fmt.Errorf("%w: %v", ErrPatchUnknownVersion, header.Version)
+	}
+
+	return &Patch{
+		ID:             header.ID,
+		ParentID:       header.ParentID,
+		CreatedBy:      header.CreatedBy,
+		Message:        header.Message,
+		Date:           header.Date,
+		Record:         header.Record,
+		Layer:          layer,
+		ParentVersions: header.ParentVersions,
+	}, nil
+}
+
+// WriteTo implements io.WriterTo.
+func (p *Patch) WriteTo(w io.Writer) (int64, error) {
+	header, err := json.MarshalIndent(patchHeader{
+		Version:        patchVersion,
+		ID:             p.ID,
+		ParentID:       p.ParentID,
+		ParentVersions: p.ParentVersions,
+		CreatedBy:      p.CreatedBy,
+		Message:        p.Message,
+		Date:           p.Date,
+		Record:         p.Record,
+	}, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to serialize patch header: %w", err)
+	}
+
+	cw := &countWriter{w: w}
+	tw := tar.NewWriter(cw)
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{
+		{patchHeaderEntry, header},
+		{patchLayerEntry, p.Layer},
+	} {
+		err = tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.name,
+			Size:     int64(len(entry.content)),
+			Mode:     0644,
+			ModTime:  p.Date,
+		})
+		if err != nil {
+			return cw.n, fmt.Errorf("failed to write patch entry %v: %w", entry.name, err)
+		}
+
+		_, err = tw.Write(entry.content)
+		if err != nil {
+			return cw.n, fmt.Errorf("failed to write patch entry %v: %w", entry.name, err)
+		}
+	}
+
+	return cw.n, tw.Close()
+}
+
+// FileName returns the file name of the patch at the given position in a
+// series of patches.
+func (p *Patch) FileName(position int) string {
+	return fmt.Sprintf("%04d-%v%v", position, patchSlug(messageSubject(p.Message)), patchExtension)
+}
+
+// patchSlug returns a file name friendly version of the given subject.
+func patchSlug(subject string) string {
+	builder := strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(subject) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && builder.Len() > 0 {
+				builder.WriteRune('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+
+		if builder.Len() >= patchSubjectMaxLen {
+			break
+		}
+	}
+
+	if builder.Len() == 0 {
+		return "patch"
+	}
+
+	return builder.String()
+}
+
+// countWriter is an io.Writer counting bytes written to the underlying writer.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write implements io.Writer.
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// FormatPatches writes a patch file per commit reachable from to reference but
+// not from from reference in the given directory. Patches are numbered from
+// older to newer commit and paths of written files are returned in the same
+// order.
+func (r *Repository) FormatPatches(from, to reference.Reference, dir string) ([]string, error) {
+	fromCommit, err := r.Commit(from)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := r.Commit(to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commits in range, from newer to older
+	commits := make([]*Commit, 0)
+	for commit := toCommit; commit.ID() != fromCommit.ID(); commit = commit.Parent() {
+		if commit.Parent() == nil {
+			return nil, ErrPatchRangeNotAncestor
+		}
+		commits = append(commits, commit)
+	}
+	if len(commits) == 0 {
+		return nil, ErrNothingToFormatPatches
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(commits))
+	for i := len(commits) - 1; i >= 0; i-- {
+		patch, err := r.formatPatch(commits[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to format patch for commit %v: %w", commits[i].ID(), err)
+		}
+
+		path := filepath.Join(dir, patch.FileName(len(paths)+1))
+		err = writePatchFile(path, patch)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+func (r *Repository) formatPatch(commit *Commit) (*Patch, error) {
+	if !commit.WasCreatedByOcitree() {
+		return nil, ErrPatchNotOcitreeCommit
+	}
+	if !hasImage(commit) || !hasImage(commit.Parent()) {
+		return nil, ErrCommitHasNoImageAssociated
+	}
+
+	parentID := commit.Parent().ID()
+	layer, err := readDiff(r.runtime.diff(parentID, commit.ID()))
+	if err != nil {
+		return nil, err
+	}
+
+	parentMountpoint, err := r.runtime.mountImage(parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer r.runtime.unmountImage(parentID)
+
+	parentVersions, err := layerPathsVersions(parentMountpoint, layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve versions of changed paths in commit %v: %w", parentID, err)
+	}
+
+	date := time.Time{}
+	if commit.CreationDate() != nil {
+		date = *commit.CreationDate()
+	}
+
+	return &Patch{
+		ID:             commit.ID(),
+		ParentID:       parentID,
+		CreatedBy:      commit.CreatedBy()[len(CommitPrefix):],
+		Message:        commit.Message(),
+		Date:           date,
+		Record:         commit.operationRecord(),
+		Layer:          layer,
+		ParentVersions: parentVersions,
+	}, nil
+}
+
+func writePatchFile(path string, patch *Patch) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create patch file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = patch.WriteTo(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// ApplyPatchesOptions holds options for Repository.ApplyPatches method.
+type ApplyPatchesOptions struct {
+	// ConflictStrategy define how conflicts between patches and changes made
+	// on HEAD since patches parent are resolved.
+	ConflictStrategy RebaseConflictStrategy
+
+	ReportWriter io.Writer
+}
+
+// ApplyPatches commits the patch files at the given paths on top of HEAD, in
+// the given order. Patches keep their operation and message. Conflicts are
+// detected by comparing versions of paths touched by a patch in its parent,
+// recorded in the patch, with their versions in HEAD, so patch parent doesn't
+// need to exist locally. If a patch can't be applied, HEAD is moved back to
+// its original position and no patch is applied.
+func (r *Repository) ApplyPatches(options ApplyPatchesOptions, paths ...string) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
+	patches := make([]*Patch, len(paths))
+	for i, path := range paths {
+		patch, err := ReadPatch(path)
+		if err != nil {
+			return fmt.Errorf("failed to read patch %v: %w", path, err)
+		}
+		patches[i] = patch
+	}
+
+	origID := r.ID()
+	refID, err := reference.IDFromString(origID)
+	if err != nil {
+		return fmt.Errorf("failed to parse HEAD ID: %w", err)
+	}
+	origHead := reference.NewLocal(r.Name(), refID)
+
+	// Maps ID of applied patches to ID of resulting commits.
+	applied := make(map[string]string)
+	for i, patch := range patches {
+		err := r.applyPatch(patch, applied, options)
+		if err != nil {
+			if r.ID() != origID {
+				abortErr := r.checkout(origHead, AmReflogOperation, "abort")
+				if abortErr != nil {
+					return fmt.Errorf("failed to move HEAD back after patch %v failed (%v): %w", paths[i], err, abortErr)
+				}
+			}
+			return fmt.Errorf("failed to apply patch %v: %w", paths[i], err)
+		}
+		applied[patch.ID] = r.ID()
+	}
+
+	return nil
+}
+
+func (r *Repository) applyPatch(patch *Patch, applied map[string]string, options ApplyPatchesOptions) error {
+	parentID := patch.ParentID
+	if id, ok := applied[parentID]; ok {
+		parentID = id
+	}
+
+	builder, err := r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	err = applyPickedLayer(builder, patch.Layer, func(mountpoint string, layer []byte) ([]byte, error) {
+		if parentID == r.ID() {
+			return layer, nil
+		}
+
+		// Paths touched by patch changed between patch parent and HEAD
+		headVersions, err := layerPathsVersions(mountpoint, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve versions of changed paths in HEAD: %w", err)
+		}
+		headChanges := fileVersionsChanges(patch.ParentVersions, headVersions)
+
+		return resolveConflicts(mountpoint, patch.ID, layer, headChanges, options.ConflictStrategy)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = replayConfig(builder, patch.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(builder, AmReflogOperation, CommitOptions{
+		CreatedBy:    patch.CreatedBy,
+		Message:      patch.Message,
+		Squash:       false,
+		Record:       patch.Record,
+		ReportWriter: options.ReportWriter,
+	})
+}
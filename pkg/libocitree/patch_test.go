@@ -0,0 +1,160 @@
+package libocitree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchSlug(t *testing.T) {
+	require.Equal(t, "add-nginx-conf", patchSlug("Add nginx.conf"))
+	require.Equal(t, "fix-1234", patchSlug("  fix #1234 !"))
+	require.Equal(t, "patch", patchSlug(""))
+}
+
+func TestRepositoryFormatAndApplyPatches(t *testing.T) {
+	_, cleanup, repo, resetRef := setupResetTest(t)
+	defer cleanup()
+
+	commits, err := repo.Commits()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	paths, err := repo.FormatPatches(resetRef, repo.HeadRef(), dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "0001-commit-1.ocipatch"),
+		filepath.Join(dir, "0002-commit-2.ocipatch"),
+		filepath.Join(dir, "0003-commit-3.ocipatch"),
+	}, paths)
+
+	t.Run("ReadPatch", func(t *testing.T) {
+		patch, err := ReadPatch(paths[2])
+		require.NoError(t, err)
+
+		require.Equal(t, commits[0].ID(), patch.ID)
+		require.Equal(t, commits[1].ID(), patch.ParentID)
+		require.Equal(t, commits[0].CreatedBy(), CommitPrefix+patch.CreatedBy)
+		require.Equal(t, "commit 3", patch.Message)
+		require.Equal(t, commits[0].CreationDate().Unix(), patch.Date.Unix())
+	})
+
+	_, err = repo.Reset(resetRef, HardResetMode)
+	require.NoError(t, err)
+
+	t.Run("Apply", func(t *testing.T) {
+		err := repo.ApplyPatches(ApplyPatchesOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, paths...)
+		require.NoError(t, err)
+
+		applied, err := repo.Commits()
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			require.Equal(t, commits[i].CreatedBy(), applied[i].CreatedBy())
+			require.Equal(t, commits[i].Message(), applied[i].Message())
+		}
+
+		requireWorkingContainerFiles(t, repo, []string{"commit1", "commit2", "commit3"}, []string{})
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		_, err := repo.Reset(resetRef, HardResetMode)
+		require.NoError(t, err)
+		headID := repo.ID()
+
+		// Second patch conflicts with the first one
+		err = repo.ApplyPatches(ApplyPatchesOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, paths[0], paths[0])
+		require.Error(t, err)
+
+		var conflictErr *RebaseConflictError
+		require.True(t, errors.As(err, &conflictErr))
+
+		// No patch applied
+		require.Equal(t, headID, repo.ID())
+	})
+}
+
+func TestRepositoryApplyPatchesWithoutParent(t *testing.T) {
+	senderManager, senderCleanup := newTestManager(t)
+	defer senderCleanup()
+	receiverManager, receiverCleanup := newTestManager(t)
+	defer receiverCleanup()
+
+	// Repositories live in distinct stores, receiver doesn't have patches
+	// parent.
+	sender := initTestRepository(t, senderManager, "localhost/ocitree/sender", time.Unix(1000, 0), map[string]string{
+		"etc/hostname": "sender\n",
+		"shared":       "shared\n",
+	})
+	receiver := initTestRepository(t, receiverManager, "localhost/ocitree/receiver", time.Unix(2000, 0), map[string]string{
+		"etc/hostname": "receiver\n",
+		"shared":       "shared\n",
+	})
+
+	baseID, err := reference.IDFromString(sender.ID())
+	require.NoError(t, err)
+	baseRef := reference.NewLocal(sender.Name(), baseID)
+
+	for _, file := range []struct {
+		dest    string
+		content string
+	}{
+		{"/picked", "picked\n"},
+		{"/shared", "shared\n"},
+		{"/etc/hostname", "patched\n"},
+	} {
+		src := filepath.Join(t.TempDir(), "src")
+		require.NoError(t, os.WriteFile(src, []byte(file.content), 0644))
+
+		err := sender.Add(file.dest, AddOptions{
+			Chmod:        "",
+			Chown:        "",
+			Message:      "add " + file.dest,
+			ReportWriter: nil,
+		}, src)
+		require.NoError(t, err)
+	}
+
+	paths, err := sender.FormatPatches(baseRef, sender.HeadRef(), t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, paths, 3)
+
+	t.Run("Apply", func(t *testing.T) {
+		// Patches touch a new file and a file identical in both
+		// repositories.
+		err := receiver.ApplyPatches(ApplyPatchesOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, paths[:2]...)
+		require.NoError(t, err)
+
+		require.Equal(t, "picked\n", readRepoFile(t, receiver, "/picked"))
+		require.Equal(t, "receiver\n", readRepoFile(t, receiver, "/etc/hostname"))
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		// /etc/hostname differs between patch parent and HEAD.
+		headID := receiver.ID()
+
+		err := receiver.ApplyPatches(ApplyPatchesOptions{
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		}, paths[2])
+
+		var conflictErr *RebaseConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		require.Len(t, conflictErr.Conflicts, 1)
+		require.Equal(t, "/etc/hostname", conflictErr.Conflicts[0].Path)
+		require.Equal(t, headID, receiver.ID())
+	})
+}
@@ -12,17 +12,24 @@ import (
 	"github.com/containers/buildah"
 	"github.com/containers/common/libimage"
 	"github.com/containers/storage/pkg/archive"
+	"github.com/containers/storage/pkg/chrootarchive"
 	"github.com/negrel/ocitree/pkg/reference"
 	"github.com/sirupsen/logrus"
 )
 
 var (
-	ErrUnknownRebaseChoice   = errors.New("unknown rebase choice")
-	ErrInvalidRebaseCommitID = errors.New("invalid rebase commit id")
-	ErrDuplicateRebaseCommit = errors.New("rebase commit line already parsed")
-	interactiveEditHelpText  = `#
+	ErrUnknownRebaseChoice    = errors.New("unknown rebase choice")
+	ErrInvalidRebaseCommitID  = errors.New("invalid rebase commit id")
+	ErrDuplicateRebaseCommit  = errors.New("rebase commit line already parsed")
+	ErrNoPreviousRebaseCommit = errors.New("no previous commit to squash into")
+	ErrMissingRebaseCommand   = errors.New("missing exec command")
+	interactiveEditHelpText   = `#
 # Commands:
 # p, pick <commit> = use commit
+# r, reword <commit> = use commit, but edit the commit message
+# s, squash <commit> = use commit, but meld into previous commit
+# f, fixup <commit> = like "squash", but discard this commit's message
+# x, exec <command> = run command (the rest of the line) using shell
 # d, drop <commit> = remove commit
 #
 # These lines can be re-ordered; they are executed from top to bottom.
@@ -31,6 +38,10 @@ var (
 #
 # However, if you remove everything, the rebase will be aborted.
 #
+`
+	rewordHelpText = `
+# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored.
 `
 )
 
@@ -39,12 +50,20 @@ type RebaseChoice uint
 const (
 	PickRebaseChoice RebaseChoice = iota
 	DropRebaseChoice
+	RewordRebaseChoice
+	SquashRebaseChoice
+	FixupRebaseChoice
+	ExecRebaseChoice
 	UnknownRebaseChoice
 )
 
 var validRebaseChoice = map[RebaseChoice]struct{}{
-	PickRebaseChoice: {},
-	DropRebaseChoice: {},
+	PickRebaseChoice:   {},
+	DropRebaseChoice:   {},
+	RewordRebaseChoice: {},
+	SquashRebaseChoice: {},
+	FixupRebaseChoice:  {},
+	ExecRebaseChoice:   {},
 }
 
 // String implements fmt.Stringer.
@@ -54,6 +73,14 @@ func (rc RebaseChoice) String() string {
 		return "pick"
 	case DropRebaseChoice:
 		return "drop"
+	case RewordRebaseChoice:
+		return "reword"
+	case SquashRebaseChoice:
+		return "squash"
+	case FixupRebaseChoice:
+		return "fixup"
+	case ExecRebaseChoice:
+		return "exec"
 	default:
 		return "unknown"
 	}
@@ -67,16 +94,53 @@ func choiceFromString(str string) RebaseChoice {
 	case "drop", "d":
 		return DropRebaseChoice
 
+	case "reword", "r":
+		return RewordRebaseChoice
+
+	case "squash", "s":
+		return SquashRebaseChoice
+
+	case "fixup", "f":
+		return FixupRebaseChoice
+
+	case "exec", "x":
+		return ExecRebaseChoice
+
 	default:
 		return UnknownRebaseChoice
 	}
 }
 
 // RebaseCommit correspond to a commit and a rebase choice.
+// Exec rebase commits have no commit attached, only a shell command.
 type RebaseCommit struct {
 	Commit
 	index  int
 	Choice RebaseChoice
+
+	// NewMessage is the commit message used when rewording or squashing
+	// commit. If empty, an editor is opened to edit the original message.
+	NewMessage string
+
+	// Command is the shell command executed by exec rebase commits.
+	Command string
+}
+
+func newExecRebaseCommit(command string) *RebaseCommit {
+	return &RebaseCommit{
+		index:   -1,
+		Choice:  ExecRebaseChoice,
+		Command: command,
+	}
+}
+
+// rewordMessage returns the new message of a reworded commit.
+func (rc *RebaseCommit) rewordMessage() (string, error) {
+	if rc.NewMessage != "" {
+		return rc.NewMessage, nil
+	}
+
+	return editMessage(rc.Message())
 }
 
 // RebaseCommits define a read only wrapper over a slice of RebaseCommit.
@@ -148,8 +212,12 @@ func (rc RebaseCommits) String() string {
 	for i, c := range rc.commits {
 		builder.WriteString(c.Choice.String())
 		builder.WriteString(" ")
-		builder.WriteString(c.Commit.ID()[:8] + " ")
-		builder.WriteString(c.Commit.Message())
+		if c.Choice == ExecRebaseChoice {
+			builder.WriteString(c.Command)
+		} else {
+			builder.WriteString(c.Commit.ID()[:8] + " ")
+			builder.WriteString(c.Commit.Message())
+		}
 		if i != rc.Len()-1 {
 			builder.WriteString("\n")
 		}
@@ -165,66 +233,104 @@ func (rc RebaseCommits) Swap(i, j int) {
 	}
 }
 
+// insert inserts the given RebaseCommit at index i.
+func (rc *RebaseCommits) insert(i int, commit *RebaseCommit) {
+	rc.commits = append(rc.commits, nil)
+	copy(rc.commits[i+1:], rc.commits[i:])
+	rc.commits[i] = commit
+}
+
+// removeExecs removes every exec RebaseCommit.
+func (rc *RebaseCommits) removeExecs() {
+	commits := rc.commits[:0]
+	for _, c := range rc.commits {
+		if c.Choice != ExecRebaseChoice {
+			commits = append(commits, c)
+		}
+	}
+	rc.commits = commits
+}
+
 type parseChoiceError struct {
-	line  string
-	cause error
+	lineNumber int
+	line       string
+	cause      error
 }
 
-func newParseChoiceError(line string, cause error) parseChoiceError {
-	return parseChoiceError{line, cause}
+func newParseChoiceError(lineNumber int, line string, cause error) parseChoiceError {
+	return parseChoiceError{lineNumber, line, cause}
 }
 
 // Error implements error.
 func (pce parseChoiceError) Error() string {
-	return fmt.Sprintf("failed to parse line %q: %v", pce.line, pce.cause.Error())
+	return fmt.Sprintf("failed to parse line %d %q: %v", pce.lineNumber, pce.line, pce.cause.Error())
 }
 
 // ParseChoices parses a multiline strnig where each line contains a choice
-// and a commit ID separated by a space. Empty lines and lines starting with
-// # are ignored.
-func (rc RebaseCommits) ParseChoices(choices string) error {
+// and a commit ID separated by a space. Exec lines contains a shell command
+// instead of a commit ID. Empty lines and lines starting with # are ignored.
+func (rc *RebaseCommits) ParseChoices(choices string) error {
 	commitParsed := make(map[string]struct{})
+	linesParsed := 0
+
+	// Exec lines are inserted while parsing
+	rc.removeExecs()
 
 	// For each line
-	for _, line := range strings.Split(choices, "\n") {
+	for i, line := range strings.Split(choices, "\n") {
+		lineNumber := i + 1
 		if line == "" || (len(line) > 0 && line[0] == '#') {
 			continue
 		}
 
 		// Split on space to parse choice
 		splitted := strings.SplitN(line, " ", 3)
+		rawChoice := splitted[0]
+		choice := choiceFromString(rawChoice)
+
+		// Exec line, checked first as command may be missing
+		if choice == ExecRebaseChoice {
+			command := strings.TrimSpace(line[len(rawChoice):])
+			if command == "" {
+				return newParseChoiceError(lineNumber, line, ErrMissingRebaseCommand)
+			}
+
+			rc.insert(linesParsed, newExecRebaseCommit(command))
+			linesParsed++
+			continue
+		}
+
 		if len(splitted) < 2 {
 			continue
 		}
 
 		// parse commit choice
-		rawChoice := splitted[0]
-		choice := choiceFromString(rawChoice)
 		if choice == UnknownRebaseChoice {
-			return newParseChoiceError(line, ErrUnknownRebaseChoice)
+			return newParseChoiceError(lineNumber, line, ErrUnknownRebaseChoice)
 		}
 
 		// Set choice
 		rawID := splitted[1]
 		commit, commitIndex := rc.GetByID(rawID)
 		if commit == nil {
-			return newParseChoiceError(line, ErrInvalidRebaseCommitID)
+			return newParseChoiceError(lineNumber, line, ErrInvalidRebaseCommitID)
 		}
 		if _, alreadyParsed := commitParsed[commit.ID()]; alreadyParsed {
-			return newParseChoiceError(line, ErrDuplicateRebaseCommit)
+			return newParseChoiceError(lineNumber, line, ErrDuplicateRebaseCommit)
 		}
 
 		commit.Choice = choice
 		commit, commitIndex = rc.GetByID(rawID)
 
 		// Swap commit order
-		rc.Swap(len(commitParsed), commitIndex)
+		rc.Swap(linesParsed, commitIndex)
 
 		commitParsed[commit.ID()] = struct{}{}
+		linesParsed++
 	}
 
 	// Missing commits are dropped
-	for i := len(commitParsed); i < rc.Len(); i++ {
+	for i := linesParsed; i < rc.Len(); i++ {
 		rc.Get(i).Choice = DropRebaseChoice
 	}
 
@@ -237,9 +343,28 @@ type RebaseSession struct {
 	repository *Repository
 	commits    RebaseCommits
 	runtime    imageRuntime
+	// step is the index of the next rebase commit to apply.
+	step int
+	// oldBaseID is the ID of the image rebased commits were based on.
+	oldBaseID        string
+	conflictStrategy RebaseConflictStrategy
+	// stepStrategies overrides conflictStrategy for the steps with the
+	// given index.
+	stepStrategies   map[int]RebaseConflictStrategy
+	baseChangesCache map[string]archive.ChangeType
+	// replay is true if commits are rebuilt by executing their operation
+	// again.
+	replay bool
+	// stdout and stderr receive output of commands executed during rebase.
+	stdout io.Writer
+	stderr io.Writer
 }
 
 func newRebaseSession(runtime imageRuntime, repo *Repository, baseImage *libimage.Image) (*RebaseSession, error) {
+	if repo.RebaseInProgress() {
+		return nil, ErrRebaseInProgress
+	}
+
 	err := baseImage.Tag(reference.NewLocal(repo.HeadRef().Name(), reference.RebaseHeadTag).String())
 	if err != nil {
 		return nil, fmt.Errorf("failed add REBASE_HEAD tag to new base: %w", err)
@@ -255,11 +380,29 @@ func newRebaseSession(runtime imageRuntime, repo *Repository, baseImage *libimag
 		return nil, fmt.Errorf("failed to check rebase commits: %w", err)
 	}
 
+	// Commits are ordered from older to newer, parent of the first one is
+	// the old base.
+	oldBaseID := ""
+	if rebaseCommits.Len() > 0 {
+		oldBaseID = rebaseCommits.Get(0).Parent().ID()
+		if oldBaseID == "<missing>" {
+			oldBaseID = ""
+		}
+	}
+
 	return &RebaseSession{
-		baseImage:  baseImage,
-		repository: repo,
-		commits:    rebaseCommits,
-		runtime:    runtime,
+		baseImage:        baseImage,
+		repository:       repo,
+		commits:          rebaseCommits,
+		runtime:          runtime,
+		step:             0,
+		oldBaseID:        oldBaseID,
+		conflictStrategy: FailRebaseConflictStrategy,
+		stepStrategies:   make(map[int]RebaseConflictStrategy),
+		baseChangesCache: nil,
+		replay:           false,
+		stdout:           nil,
+		stderr:           nil,
 	}, nil
 }
 
@@ -269,14 +412,25 @@ func (rs *RebaseSession) BaseImage() *libimage.Image {
 }
 
 // Commits returns the RebaseCommits part of this session.
-func (rs *RebaseSession) Commits() RebaseCommits {
-	return rs.commits
+func (rs *RebaseSession) Commits() *RebaseCommits {
+	return &rs.commits
+}
+
+// SetOutput sets writers receiving standard output and error of commands
+// executed during rebase. Output is written to process standard output and
+// error if they're nil.
+func (rs *RebaseSession) SetOutput(stdout, stderr io.Writer) {
+	rs.stdout = stdout
+	rs.stderr = stderr
 }
 
 // Apply applies rebase choice. RebaseSession must no be used
-// after this method has been called.
+// after this method has been called. If an error occurs, rebase stays in
+// progress and can be resumed using Continue or Skip, or aborted using
+// Abort.
 func (rs *RebaseSession) Apply() error {
 	// Validate commits before executing them
+	hasPreviousCommit := false
 	for i := 0; i < rs.commits.Len(); i++ {
 		commit := rs.commits.Get(i)
 
@@ -284,11 +438,25 @@ func (rs *RebaseSession) Apply() error {
 			return ErrUnknownRebaseChoice
 		}
 
-		if commit.Choice == PickRebaseChoice {
-			if commit.Commit.ID() == "" {
-				return fmt.Errorf("can't apply commit number %d: can't pick a commit with no associated layer id", i)
+		if commit.Choice == DropRebaseChoice {
+			continue
+		}
+
+		if commit.Choice == ExecRebaseChoice {
+			hasPreviousCommit = true
+			continue
+		}
+
+		if commit.Commit.ID() == "" {
+			return fmt.Errorf("can't apply commit number %d: can't pick a commit with no associated layer id", i)
+		}
+
+		if commit.Choice == SquashRebaseChoice || commit.Choice == FixupRebaseChoice {
+			if !hasPreviousCommit {
+				return fmt.Errorf("can't apply commit number %d: %w", i, ErrNoPreviousRebaseCommit)
 			}
 		}
+		hasPreviousCommit = true
 	}
 
 	// Nothing to do
@@ -296,6 +464,18 @@ func (rs *RebaseSession) Apply() error {
 		return nil
 	}
 
+	// Persist session so it can be resumed
+	rs.step = 0
+	err := rs.saveState()
+	if err != nil {
+		return err
+	}
+
+	return rs.resume()
+}
+
+// resume applies remaining rebase choices and finishes the rebase.
+func (rs *RebaseSession) resume() error {
 	// Apply rebase choice
 	err := rs.apply()
 	if err != nil {
@@ -303,11 +483,21 @@ func (rs *RebaseSession) Apply() error {
 	}
 
 	// Move HEAD reference
-	err = rs.repository.Checkout(rs.RebaseHead())
+	err = rs.repository.checkout(rs.RebaseHead(), RebaseReflogOperation, "onto "+rs.baseImage.ID())
 	if err != nil {
 		return fmt.Errorf("failed to checkout to rebase head: %w", err)
 	}
 
+	return rs.cleanup()
+}
+
+// cleanup removes rebase state and REBASE_HEAD reference.
+func (rs *RebaseSession) cleanup() error {
+	err := rs.removeState()
+	if err != nil {
+		return err
+	}
+
 	// Remove REBASE_HEAD reference
 	err = rs.repository.removeLocalTag(reference.RebaseHeadTag)
 	if err != nil {
@@ -320,7 +510,7 @@ func (rs *RebaseSession) Apply() error {
 func (rs *RebaseSession) apply() error {
 	// Execute rebase
 	logrus.Debugf("commits:\n%v", rs.commits)
-	for i := 0; i < rs.commits.Len(); i++ {
+	for i := rs.step; i < rs.commits.Len(); i++ {
 		commit := rs.commits.Get(i)
 		// drop commit
 		if commit.Choice == DropRebaseChoice {
@@ -328,47 +518,167 @@ func (rs *RebaseSession) apply() error {
 			continue
 		}
 
-		// Create builder
-		builder, err := rs.builder()
+		// Save current step
+		rs.step = i
+		err := rs.saveState()
 		if err != nil {
-			return fmt.Errorf("failed to create builder for commit %v (%v): %w", i, commit.ID(), err)
+			return err
 		}
 
-		switch commit.Choice {
-		case PickRebaseChoice:
-			logrus.Infof("picking commit %v (%v)", i, commit.Commit.ID())
-			err := rs.pick(builder, commit)
-			if err != nil {
-				return fmt.Errorf("failed to pick commit %v (%v): %w", i, commit.Commit.ID(), err)
-			}
+		i, err = rs.applyCommit(i)
+		if err != nil {
+			return err
+		}
+	}
 
-		default:
-			return ErrUnknownRebaseChoice
+	return nil
+}
+
+// applyCommit applies rebase commit at index i and melds following squash and
+// fixup commits into it. Index of the last applied rebase commit is returned.
+func (rs *RebaseSession) applyCommit(i int) (int, error) {
+	commit := rs.commits.Get(i)
+
+	// Create builder
+	builder, err := rs.builder()
+	if err != nil {
+		return i, fmt.Errorf("failed to create builder for commit %v (%v): %w", i, commit.ID(), err)
+	}
+	defer builder.Delete()
+
+	commitOptions := CommitOptions{
+		CreatedBy:    "",
+		Message:      commit.Message(),
+		Squash:       false,
+		Record:       nil,
+		ReportWriter: os.Stderr,
+	}
+	if commit.Choice != ExecRebaseChoice {
+		commitOptions.CreatedBy = commit.CreatedBy()[len(CommitPrefix):]
+		commitOptions.Record = commit.Commit.operationRecord()
+	}
+
+	switch commit.Choice {
+	case PickRebaseChoice:
+		logrus.Infof("picking commit %v (%v)", i, commit.Commit.ID())
+		err := rs.pick(builder, commit)
+		if err != nil {
+			return i, fmt.Errorf("failed to pick commit %v (%v): %w", i, commit.Commit.ID(), err)
 		}
 
-		// Commit rebase head
-		err = rs.commitRebaseHead(builder, CommitOptions{
-			CreatedBy:    commit.CreatedBy()[len(CommitPrefix):],
-			Message:      commit.Message(),
-			ReportWriter: os.Stderr,
-		})
+	case RewordRebaseChoice:
+		logrus.Infof("rewording commit %v (%v)", i, commit.Commit.ID())
+		err := rs.pick(builder, commit)
+		if err != nil {
+			return i, fmt.Errorf("failed to pick commit %v (%v): %w", i, commit.Commit.ID(), err)
+		}
+
+		commitOptions.Message, err = commit.rewordMessage()
+		if err != nil {
+			return i, fmt.Errorf("failed to reword commit %v (%v): %w", i, commit.Commit.ID(), err)
+		}
+
+	case ExecRebaseChoice:
+		logrus.Infof("executing %q", commit.Command)
+		command := []string{"/bin/sh", "-c", commit.Command}
+		err := run(builder, command, ExecOptions{
+			Stdin:  nil,
+			Stdout: rs.stdout,
+			Stderr: rs.stderr,
+		}, rs.runtime.systemContext())
 		if err != nil {
-			return fmt.Errorf("failed to commit rebase head: %w", err)
+			return i, fmt.Errorf("failed to exec %q (line %v): %w", commit.Command, i, err)
 		}
 
-		// Delete builder
-		err = builder.Delete()
+		commitOptions.CreatedBy = ExecCommitOperation.String() + " " + stringList(command).String()
+		commitOptions.Record, err = newExecOperationRecord(command)
 		if err != nil {
-			return fmt.Errorf("failed to delete rebase container: %w", err)
+			return i, err
 		}
+
+	default:
+		return i, ErrUnknownRebaseChoice
 	}
 
-	return nil
+	// Meld following squash and fixup commits
+	melded := false
+	squashed := false
+	squashMessage := ""
+	for ; i+1 < rs.commits.Len(); i++ {
+		next := rs.commits.Get(i + 1)
+
+		if next.Choice == DropRebaseChoice {
+			logrus.Infof("dropping commit %v (%v)", i+1, next.ID())
+			continue
+		}
+		if next.Choice != SquashRebaseChoice && next.Choice != FixupRebaseChoice {
+			break
+		}
+
+		logrus.Infof("%v commit %v (%v) into previous commit", next.Choice, i+1, next.ID())
+		err := rs.pick(builder, next)
+		if err != nil {
+			return i, fmt.Errorf("failed to %v commit %v (%v): %w", next.Choice, i+1, next.ID(), err)
+		}
+		melded = true
+
+		if next.Choice == SquashRebaseChoice {
+			squashed = true
+			commitOptions.Message += "\n\n" + next.Message()
+			if next.NewMessage != "" {
+				squashMessage = next.NewMessage
+			}
+		}
+	}
+
+	// Arguments of the first operation don't describe melded commits.
+	if melded {
+		commitOptions.Record = operationRecordFromCreatedBy(commitOptions.CreatedBy)
+	}
+
+	if squashed {
+		if squashMessage == "" {
+			squashMessage, err = editMessage(strings.TrimSpace(commitOptions.Message))
+			if err != nil {
+				return i, fmt.Errorf("failed to edit squashed commit message: %w", err)
+			}
+		}
+		commitOptions.Message = squashMessage
+	}
+
+	// Commit rebase head
+	err = rs.commitRebaseHead(builder, commitOptions)
+	if err != nil {
+		return i, fmt.Errorf("failed to commit rebase head: %w", err)
+	}
+
+	return i, nil
 }
 
 func (rs *RebaseSession) pick(builder *buildah.Builder, commit *RebaseCommit) error {
+	if rs.replay {
+		replayed, err := rs.replayCommit(builder, commit)
+		if err != nil {
+			return err
+		}
+		if replayed {
+			return nil
+		}
+
+		logrus.Warnf("operation of commit %v can't be replayed, applying its layer diff", commit.ID())
+	}
+
+	return pick(rs.runtime, builder, &commit.Commit, func(mountpoint string, layer []byte) ([]byte, error) {
+		// Detect conflicts with new base
+		return rs.resolveConflicts(mountpoint, commit, layer)
+	})
+}
+
+// pick applies layer diff of the given commit on builder container. resolve
+// is called with builder mountpoint and layer diff before applying it.
+func pick(runtime imageRuntime, builder *buildah.Builder, commit *Commit, resolve func(mountpoint string, layer []byte) ([]byte, error)) error {
 	// Compute diff
-	diff, err := rs.runtime.diff(commit.Parent(), &commit.Commit)
+	diff, err := runtime.diff(commit.Parent().ID(), commit.ID())
 	if err != nil {
 		return fmt.Errorf("failed to compute diff between commit %v and %v: %w", commit.Parent().ID(), commit.ID(), err)
 	}
@@ -380,20 +690,42 @@ func (rs *RebaseSession) pick(builder *buildah.Builder, commit *RebaseCommit) er
 	}
 	diff.Close()
 
+	err = applyPickedLayer(builder, diffClone, resolve)
+	if err != nil {
+		return err
+	}
+
+	// Config commits changes lives in image configuration, not in layer.
+	err = replayConfig(builder, commit.CreatedBy())
+	if err != nil {
+		return err
+	}
+
+	builder.SetCreatedBy(commit.CreatedBy())
+
+	return nil
+}
+
+// applyPickedLayer applies the given layer on builder container once resolve
+// returned.
+func applyPickedLayer(builder *buildah.Builder, layer []byte, resolve func(mountpoint string, layer []byte) ([]byte, error)) error {
 	// Mount builder container
 	dstMountpoint, err := builder.Mount("")
 	if err != nil {
-		return fmt.Errorf("failed to mount rebase builder container: %w", err)
+		return fmt.Errorf("failed to mount builder container: %w", err)
 	}
 	defer builder.Unmount()
 
-	// Apply diff
-	_, err = archive.ApplyLayer(dstMountpoint, bytes.NewBuffer(diffClone))
+	layer, err = resolve(dstMountpoint, layer)
 	if err != nil {
-		return fmt.Errorf("failed to apply layer: %w", err)
+		return err
 	}
 
-	builder.SetCreatedBy(commit.CreatedBy())
+	// Apply diff, chrooted as layer may come from a third party patch.
+	_, err = chrootarchive.ApplyLayer(dstMountpoint, bytes.NewBuffer(layer))
+	if err != nil {
+		return fmt.Errorf("failed to apply layer: %w", err)
+	}
 
 	return nil
 }
@@ -467,6 +799,42 @@ func (rs *RebaseSession) InteractiveEdit() error {
 	return nil
 }
 
+// editMessage opens an editor to edit the given commit message and returns
+// the edited message. Lines starting with # are ignored.
+func editMessage(message string) (string, error) {
+	f, err := os.CreateTemp(os.TempDir(), "ocitree-message-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create commit message file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	f.WriteString(message)
+	f.WriteString("\n")
+	f.WriteString(rewordHelpText)
+
+	err = edit(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to exec commit message editor: %w", err)
+	}
+
+	// Read message
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit message file: %w", err)
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(b), "\n") {
+		if len(line) > 0 && line[0] == '#' {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
 func edit(file string) error {
 	// Try to execute $EDITOR editor
 	editor := os.Getenv("EDITOR")
@@ -0,0 +1,125 @@
+package libocitree
+
+import (
+	"strings"
+)
+
+const (
+	fixupMessagePrefix  = "fixup! "
+	squashMessagePrefix = "squash! "
+)
+
+// autosquashTarget returns the rebase choice and the subject of the target
+// commit encoded in the given commit message. false is returned if message
+// doesn't starts with "fixup! " or "squash! ".
+func autosquashTarget(message string) (RebaseChoice, string, bool) {
+	subject := messageSubject(message)
+
+	var choice RebaseChoice
+	switch {
+	case strings.HasPrefix(subject, fixupMessagePrefix):
+		choice = FixupRebaseChoice
+	case strings.HasPrefix(subject, squashMessagePrefix):
+		choice = SquashRebaseChoice
+	default:
+		return UnknownRebaseChoice, "", false
+	}
+
+	// Strip nested prefixes (e.g. "fixup! fixup! msg") so every commit
+	// targets the original one.
+	for {
+		if strings.HasPrefix(subject, fixupMessagePrefix) {
+			subject = subject[len(fixupMessagePrefix):]
+		} else if strings.HasPrefix(subject, squashMessagePrefix) {
+			subject = subject[len(squashMessagePrefix):]
+		} else {
+			break
+		}
+	}
+
+	return choice, strings.TrimSpace(subject), true
+}
+
+// messageSubject returns the first line of the given commit message.
+func messageSubject(message string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+}
+
+// findAutosquashTarget returns the first rebase commit before index i whose
+// subject matches the given one. Exact subjects are preferred over subject
+// prefixes and commit ID prefixes.
+func (rc RebaseCommits) findAutosquashTarget(i int, subject string) *RebaseCommit {
+	if subject == "" {
+		return nil
+	}
+
+	matchers := []func(c *RebaseCommit) bool{
+		func(c *RebaseCommit) bool { return messageSubject(c.Message()) == subject },
+		func(c *RebaseCommit) bool { return strings.HasPrefix(messageSubject(c.Message()), subject) },
+		func(c *RebaseCommit) bool { return strings.HasPrefix(c.ID(), subject) },
+	}
+
+	for _, match := range matchers {
+		for j := 0; j < i; j++ {
+			c := rc.commits[j]
+			if c.Choice == ExecRebaseChoice {
+				continue
+			}
+			if _, _, isAutosquash := autosquashTarget(c.Message()); isAutosquash {
+				continue
+			}
+
+			if match(c) {
+				return c
+			}
+		}
+	}
+
+	return nil
+}
+
+// Autosquash moves commits whose message starts with "fixup! <subject>" or
+// "squash! <subject>" right after the older commit matching <subject> and
+// marks them as fixup or squash. Commits without a matching target are left
+// untouched.
+func (rc *RebaseCommits) Autosquash() {
+	followers := make(map[*RebaseCommit][]*RebaseCommit)
+	moved := make(map[*RebaseCommit]struct{})
+
+	for i, commit := range rc.commits {
+		if commit.Choice != PickRebaseChoice {
+			continue
+		}
+
+		choice, subject, isAutosquash := autosquashTarget(commit.Message())
+		if !isAutosquash {
+			continue
+		}
+
+		target := rc.findAutosquashTarget(i, subject)
+		if target == nil {
+			continue
+		}
+
+		commit.Choice = choice
+		followers[target] = append(followers[target], commit)
+		moved[commit] = struct{}{}
+	}
+
+	commits := make([]*RebaseCommit, 0, len(rc.commits))
+	for _, commit := range rc.commits {
+		if _, isMoved := moved[commit]; isMoved {
+			continue
+		}
+
+		commits = append(commits, commit)
+		commits = append(commits, followers[commit]...)
+	}
+	rc.commits = commits
+}
+
+// Autosquash reorders and marks fixup and squash commits of this session.
+// See RebaseCommits.Autosquash.
+func (rs *RebaseSession) Autosquash() {
+	rs.commits.Autosquash()
+}
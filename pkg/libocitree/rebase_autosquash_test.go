@@ -0,0 +1,136 @@
+package libocitree
+
+import (
+	"testing"
+
+	"github.com/containers/common/libimage"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRebaseCommits(messages ...string) RebaseCommits {
+	commits := RebaseCommits{
+		commits: make([]*RebaseCommit, len(messages)),
+	}
+
+	for i, msg := range messages {
+		commits.commits[i] = &RebaseCommit{
+			Commit: Commit{
+				history: libimage.ImageHistory{
+					ID:      randomString(64),
+					Comment: msg + "\n",
+				},
+				parent: nil,
+			},
+			index:  i,
+			Choice: PickRebaseChoice,
+		}
+	}
+
+	return commits
+}
+
+func requireRebaseCommits(t *testing.T, commits RebaseCommits, expected ...string) {
+	actual := make([]string, commits.Len())
+	for i := 0; i < commits.Len(); i++ {
+		commit := commits.Get(i)
+		actual[i] = commit.Choice.String() + " " + messageSubject(commit.Message())
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestRebaseCommitsAutosquash(t *testing.T) {
+	t.Run("NoAutosquashCommits", func(t *testing.T) {
+		commits := newTestRebaseCommits("commit 1", "commit 2")
+		commits.Autosquash()
+
+		requireRebaseCommits(t, commits, "pick commit 1", "pick commit 2")
+	})
+
+	t.Run("FixupAndSquash", func(t *testing.T) {
+		commits := newTestRebaseCommits(
+			"install nginx",
+			"install curl",
+			"fixup! install nginx",
+			"squash! install curl",
+			"squash! install nginx",
+		)
+		commits.Autosquash()
+
+		requireRebaseCommits(t, commits,
+			"pick install nginx",
+			"fixup fixup! install nginx",
+			"squash squash! install nginx",
+			"pick install curl",
+			"squash squash! install curl",
+		)
+	})
+
+	t.Run("NestedFixup", func(t *testing.T) {
+		commits := newTestRebaseCommits(
+			"install nginx",
+			"install curl",
+			"fixup! install nginx",
+			"fixup! fixup! install nginx",
+		)
+		commits.Autosquash()
+
+		requireRebaseCommits(t, commits,
+			"pick install nginx",
+			"fixup fixup! install nginx",
+			"fixup fixup! fixup! install nginx",
+			"pick install curl",
+		)
+	})
+
+	t.Run("SubjectPrefixAndID", func(t *testing.T) {
+		commits := newTestRebaseCommits(
+			"install nginx and configure it",
+			"install curl",
+			"fixup! install nginx",
+			"",
+		)
+		// Target commit using its ID prefix
+		curlID := commits.Get(1).ID()[:8]
+		commits.Get(3).history.Comment = "fixup! " + curlID + "\n"
+		commits.Autosquash()
+
+		requireRebaseCommits(t, commits,
+			"pick install nginx and configure it",
+			"fixup fixup! install nginx",
+			"pick install curl",
+			"fixup fixup! "+curlID,
+		)
+	})
+
+	t.Run("UnknownTarget", func(t *testing.T) {
+		commits := newTestRebaseCommits(
+			"fixup! install nginx",
+			"install nginx",
+			"fixup! install curl",
+		)
+		commits.Autosquash()
+
+		// Targets must be older than fixup commits
+		requireRebaseCommits(t, commits,
+			"pick fixup! install nginx",
+			"pick install nginx",
+			"pick fixup! install curl",
+		)
+	})
+
+	t.Run("MultilineMessage", func(t *testing.T) {
+		commits := newTestRebaseCommits(
+			"install nginx\n\nand enable it",
+			"install curl",
+			"squash! install nginx\n\ndon't enable it",
+		)
+		commits.Autosquash()
+
+		requireRebaseCommits(t, commits,
+			"pick install nginx",
+			"squash squash! install nginx",
+			"pick install curl",
+		)
+	})
+}
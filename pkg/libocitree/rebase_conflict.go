@@ -0,0 +1,382 @@
+package libocitree
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/sirupsen/logrus"
+)
+
+// RebaseConflictStrategy define how conflicts between picked commits and
+// new base are resolved.
+type RebaseConflictStrategy uint
+
+const (
+	// FailRebaseConflictStrategy stops the rebase on conflicts.
+	FailRebaseConflictStrategy RebaseConflictStrategy = iota
+	// OursRebaseConflictStrategy keeps version of the new base.
+	OursRebaseConflictStrategy
+	// TheirsRebaseConflictStrategy keeps version of the picked commit.
+	TheirsRebaseConflictStrategy
+	UnknownRebaseConflictStrategy
+)
+
+// String implements fmt.Stringer.
+func (rcs RebaseConflictStrategy) String() string {
+	switch rcs {
+	case FailRebaseConflictStrategy:
+		return "fail"
+	case OursRebaseConflictStrategy:
+		return "ours"
+	case TheirsRebaseConflictStrategy:
+		return "theirs"
+	default:
+		return "unknown"
+	}
+}
+
+// RebaseConflictStrategyFromString parses the given rebase conflict strategy.
+func RebaseConflictStrategyFromString(str string) RebaseConflictStrategy {
+	switch strings.ToLower(str) {
+	case "fail", "":
+		return FailRebaseConflictStrategy
+	case "ours":
+		return OursRebaseConflictStrategy
+	case "theirs":
+		return TheirsRebaseConflictStrategy
+	default:
+		return UnknownRebaseConflictStrategy
+	}
+}
+
+// RebaseConflict define a path modified by both a picked commit and
+// the new base (or by later commits when reverting).
+type RebaseConflict struct {
+	Path string
+	// Base is the version of the file in the new base or HEAD.
+	Base FileVersion
+	// Commit is the version of the file in the picked commit.
+	Commit FileVersion
+}
+
+// String implements fmt.Stringer.
+func (rc RebaseConflict) String() string {
+	return fmt.Sprintf("%v (base: %v, commit: %v)", rc.Path, rc.Base, rc.Commit)
+}
+
+// RebaseConflictError is returned when a picked or reverted commit conflicts
+// with the new base or HEAD.
+type RebaseConflictError struct {
+	CommitID  string
+	Conflicts []RebaseConflict
+}
+
+// Error implements error.
+func (rce *RebaseConflictError) Error() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "commit %v conflicts on %d path(s):", rce.CommitID, len(rce.Conflicts))
+	for _, conflict := range rce.Conflicts {
+		builder.WriteString("\n\t")
+		builder.WriteString(conflict.String())
+	}
+
+	return builder.String()
+}
+
+// SetConflictStrategy sets the strategy used to resolve conflicts.
+func (rs *RebaseSession) SetConflictStrategy(strategy RebaseConflictStrategy) {
+	rs.conflictStrategy = strategy
+}
+
+// ConflictStrategy returns the strategy used to resolve conflicts.
+func (rs *RebaseSession) ConflictStrategy() RebaseConflictStrategy {
+	return rs.conflictStrategy
+}
+
+// SetStepConflictStrategy sets the strategy used to resolve conflicts of the
+// current step only, following steps use ConflictStrategy. It is meant to
+// resolve conflicts that interrupted the rebase before calling Continue.
+func (rs *RebaseSession) SetStepConflictStrategy(strategy RebaseConflictStrategy) {
+	rs.stepStrategies[rs.step] = strategy
+}
+
+// stepConflictStrategy returns the strategy used to resolve conflicts of the
+// current step.
+func (rs *RebaseSession) stepConflictStrategy() RebaseConflictStrategy {
+	if strategy, ok := rs.stepStrategies[rs.step]; ok {
+		return strategy
+	}
+
+	return rs.conflictStrategy
+}
+
+// baseChanges returns paths changed between old and new base.
+func (rs *RebaseSession) baseChanges() (map[string]archive.ChangeType, error) {
+	if rs.baseChangesCache != nil {
+		return rs.baseChangesCache, nil
+	}
+
+	rs.baseChangesCache = make(map[string]archive.ChangeType)
+	if rs.oldBaseID == "" || rs.oldBaseID == rs.baseImage.ID() {
+		return rs.baseChangesCache, nil
+	}
+
+	changes, err := rs.runtime.changes(rs.oldBaseID, rs.baseImage.ID())
+	if err != nil {
+		rs.baseChangesCache = nil
+		return nil, fmt.Errorf("failed to compute changes between old and new base: %w", err)
+	}
+
+	for _, change := range changes {
+		rs.baseChangesCache[change.Path] = change.Kind
+	}
+
+	return rs.baseChangesCache, nil
+}
+
+// resolveConflicts detects conflicts between the given commit layer and new
+// base and resolves them using conflict strategy. Layer to apply is returned.
+func (rs *RebaseSession) resolveConflicts(mountpoint string, commit *RebaseCommit, layer []byte) ([]byte, error) {
+	baseChanges, err := rs.baseChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveConflicts(mountpoint, commit.ID(), layer, baseChanges, rs.stepConflictStrategy())
+}
+
+// resolveConflicts detects conflicts between the given commit layer and
+// changes made to the rootfs mounted at mountpoint. Conflicts are resolved
+// using the given strategy. Layer to apply is returned.
+func resolveConflicts(mountpoint, commitID string, layer []byte, changes map[string]archive.ChangeType, strategy RebaseConflictStrategy) ([]byte, error) {
+	if len(changes) == 0 {
+		return layer, nil
+	}
+
+	conflicts := make([]RebaseConflict, 0)
+	conflictingEntries := make(map[string]struct{})
+
+	tr := tar.NewReader(bytes.NewReader(layer))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer: %w", err)
+		}
+
+		p, _, isOpaque := layerEntryPath(hdr)
+		if !isConflicting(mountpoint, p, hdr, isOpaque, changes) {
+			continue
+		}
+
+		basePath, err := rootfsPath(mountpoint, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %v: %w", p, err)
+		}
+		baseVersion, err := fileVersionFromPath(basePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve base version of %v: %w", p, err)
+		}
+		commitVersion, err := fileVersionFromTar(hdr, tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve commit version of %v: %w", p, err)
+		}
+
+		conflicts = append(conflicts, RebaseConflict{
+			Path:   p,
+			Base:   baseVersion,
+			Commit: commitVersion,
+		})
+		conflictingEntries[hdr.Name] = struct{}{}
+	}
+
+	if len(conflicts) == 0 {
+		return layer, nil
+	}
+
+	switch strategy {
+	case OursRebaseConflictStrategy:
+		for _, conflict := range conflicts {
+			logrus.Warnf("conflict on %v, keeping base version", conflict)
+		}
+		return filterLayer(layer, func(hdr *tar.Header) bool {
+			_, isConflicting := conflictingEntries[hdr.Name]
+			return !isConflicting
+		})
+
+	case TheirsRebaseConflictStrategy:
+		for _, conflict := range conflicts {
+			logrus.Warnf("conflict on %v, keeping commit version", conflict)
+		}
+		return layer, nil
+
+	default:
+		return nil, &RebaseConflictError{
+			CommitID:  commitID,
+			Conflicts: conflicts,
+		}
+	}
+}
+
+// isConflicting returns true if the given layer entry conflicts with changes.
+func isConflicting(mountpoint, p string, hdr *tar.Header, isOpaque bool, changes map[string]archive.ChangeType) bool {
+	// Opaque directory conflicts with any change inside it.
+	if isOpaque {
+		for changePath := range changes {
+			if changePath != p && isPathUnder(changePath, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if _, changed := changes[p]; !changed {
+		return false
+	}
+
+	// Directories modified by both sides don't conflict, only their content.
+	if hdr.Typeflag == tar.TypeDir {
+		basePath, err := rootfsPath(mountpoint, p)
+		if err != nil {
+			return true
+		}
+		baseVersion, err := fileVersionFromPath(basePath)
+		if err == nil && baseVersion.Mode.IsDir() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// layerPathsChanges returns changes between rootfs mounted at fromMountpoint
+// and toMountpoint restricted to paths touched by the given layer. Content of
+// opaque directories is compared recursively.
+func layerPathsChanges(fromMountpoint, toMountpoint string, layer []byte) (map[string]archive.ChangeType, error) {
+	fromVersions, err := layerPathsVersions(fromMountpoint, layer)
+	if err != nil {
+		return nil, err
+	}
+	toVersions, err := layerPathsVersions(toMountpoint, layer)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileVersionsChanges(fromVersions, toVersions), nil
+}
+
+// layerPathsVersions returns versions of paths touched by the given layer in
+// rootfs mounted at mountpoint. Content of opaque directories is walked
+// recursively.
+func layerPathsVersions(mountpoint string, layer []byte) (map[string]FileVersion, error) {
+	versions := make(map[string]FileVersion)
+
+	tr := tar.NewReader(bytes.NewReader(layer))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer: %w", err)
+		}
+
+		p, _, isOpaque := layerEntryPath(hdr)
+		root, err := rootfsPath(mountpoint, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %v: %w", p, err)
+		}
+
+		if !isOpaque {
+			versions[p], err = fileVersionFromPath(root)
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve version of %v: %w", p, err)
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(walkPath string, _ fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, walkPath)
+			if err != nil {
+				return err
+			}
+			entryPath := path.Join(p, filepath.ToSlash(rel))
+
+			versions[entryPath], err = fileVersionFromPath(walkPath)
+			if err != nil {
+				return fmt.Errorf("failed to retrieve version of %v: %w", entryPath, err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk opaque directory %v: %w", p, err)
+		}
+	}
+
+	return versions, nil
+}
+
+// fileVersionsChanges returns changes between the given path versions. Paths
+// missing from one of the maps are considered deleted in it.
+func fileVersionsChanges(from, to map[string]FileVersion) map[string]archive.ChangeType {
+	changes := make(map[string]archive.ChangeType)
+	deleted := FileVersion{Deleted: true}
+
+	for p, fromVersion := range from {
+		toVersion, ok := to[p]
+		if !ok {
+			toVersion = deleted
+		}
+		if kind, changed := fileVersionsChange(fromVersion, toVersion); changed {
+			changes[p] = kind
+		}
+	}
+	for p, toVersion := range to {
+		if _, ok := from[p]; ok {
+			continue
+		}
+		if kind, changed := fileVersionsChange(deleted, toVersion); changed {
+			changes[p] = kind
+		}
+	}
+
+	return changes
+}
+
+// fileVersionsChange returns the kind of change between the given file
+// versions. False is returned if file is unchanged, directories with the same
+// mode are considered unchanged.
+func fileVersionsChange(from, to FileVersion) (archive.ChangeType, bool) {
+	switch {
+	case from.Deleted && to.Deleted:
+		return archive.ChangeModify, false
+	case from.Deleted:
+		return archive.ChangeAdd, true
+	case to.Deleted:
+		return archive.ChangeDelete, true
+	case from.Mode.IsDir() && from.Mode == to.Mode:
+		return archive.ChangeModify, false
+	case from == to:
+		return archive.ChangeModify, false
+	default:
+		return archive.ChangeModify, true
+	}
+}
@@ -0,0 +1,149 @@
+package libocitree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+const conflictingFileContent = "patched\n"
+
+// setupRebaseConflictTest clones alpine:3.15, patches /etc/alpine-release and
+// returns a rebase session onto alpine:3.16 which also modifies that file.
+func setupRebaseConflictTest(t *testing.T) (*Manager, func(), *Repository, *RebaseSession) {
+	manager, cleanup := newTestManager(t)
+
+	ref, err := reference.RemoteRefFromString("alpine:3.15")
+	require.NoError(t, err)
+
+	pullOptions := PullOptions{
+		MaxRetries:   0,
+		RetryDelay:   0,
+		ReportWriter: os.Stderr,
+	}
+
+	err = manager.Clone(ref, CloneOptions{PullOptions: pullOptions})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	err = repo.Exec(ExecOptions{
+		Stdin:        nil,
+		Stdout:       nil,
+		Stderr:       nil,
+		Message:      "patch alpine-release",
+		ReportWriter: nil,
+	}, "/bin/sh", "-c", "echo patched > /etc/alpine-release && touch /commit1")
+	require.NoError(t, err)
+
+	rebaseRef, err := reference.RemoteRefFromString("alpine:3.16")
+	require.NoError(t, err)
+	err = manager.Fetch(rebaseRef, FetchOptions{PullOptions: pullOptions})
+	require.NoError(t, err)
+
+	session, err := repo.RebaseSession(rebaseRef)
+	require.NoError(t, err)
+
+	return manager, cleanup, repo, session
+}
+
+func readRepoFile(t *testing.T, repo *Repository, p string) string {
+	err := repo.ReloadHead()
+	require.NoError(t, err)
+
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+	defer repo.Unmount()
+
+	content, err := os.ReadFile(filepath.Join(mountpoint, p))
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestRebaseConflictFail(t *testing.T) {
+	_, cleanup, repo, session := setupRebaseConflictTest(t)
+	defer cleanup()
+
+	err := session.Apply()
+	require.Error(t, err)
+
+	conflictErr := &RebaseConflictError{}
+	require.True(t, errors.As(err, &conflictErr), "error isn't a conflict error")
+	require.Len(t, conflictErr.Conflicts, 1)
+
+	conflict := conflictErr.Conflicts[0]
+	require.Equal(t, "/etc/alpine-release", conflict.Path)
+	require.False(t, conflict.Commit.Deleted)
+	require.Equal(t, digest.FromString(conflictingFileContent), conflict.Commit.Digest)
+	require.False(t, conflict.Base.Deleted)
+	require.NotEqual(t, conflict.Commit.Digest, conflict.Base.Digest)
+
+	// Rebase is stopped
+	require.True(t, repo.RebaseInProgress())
+
+	session, err = repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+	err = session.Abort()
+	require.NoError(t, err)
+}
+
+func TestRebaseConflictOurs(t *testing.T) {
+	_, cleanup, repo, session := setupRebaseConflictTest(t)
+	defer cleanup()
+
+	session.SetConflictStrategy(OursRebaseConflictStrategy)
+	err := session.Apply()
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(readRepoFile(t, repo, "etc/alpine-release"), "3.16"))
+	readRepoFile(t, repo, "commit1")
+}
+
+func TestRebaseConflictTheirs(t *testing.T) {
+	_, cleanup, repo, session := setupRebaseConflictTest(t)
+	defer cleanup()
+
+	session.SetConflictStrategy(TheirsRebaseConflictStrategy)
+	err := session.Apply()
+	require.NoError(t, err)
+
+	require.Equal(t, conflictingFileContent, readRepoFile(t, repo, "etc/alpine-release"))
+	readRepoFile(t, repo, "commit1")
+}
+
+func TestRebaseConflictContinue(t *testing.T) {
+	_, cleanup, repo, session := setupRebaseConflictTest(t)
+	defer cleanup()
+
+	err := session.Apply()
+	conflictErr := &RebaseConflictError{}
+	require.True(t, errors.As(err, &conflictErr), "error isn't a conflict error")
+
+	// Retrying without resolving conflicts stops again
+	session, err = repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+	err = session.Continue()
+	require.True(t, errors.As(err, &conflictErr), "error isn't a conflict error")
+	require.True(t, repo.RebaseInProgress())
+
+	// Resolve conflicts of the interrupted step only
+	session, err = repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+	session.SetStepConflictStrategy(TheirsRebaseConflictStrategy)
+	require.Equal(t, FailRebaseConflictStrategy, session.ConflictStrategy())
+
+	err = session.Continue()
+	require.NoError(t, err)
+	require.False(t, repo.RebaseInProgress())
+
+	require.Equal(t, conflictingFileContent, readRepoFile(t, repo, "etc/alpine-release"))
+	readRepoFile(t, repo, "commit1")
+}
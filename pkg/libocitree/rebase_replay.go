@@ -0,0 +1,110 @@
+package libocitree
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/containers/buildah"
+)
+
+var (
+	ErrReplayAddSourceNotFound = errors.New("ADD source no longer exists")
+)
+
+// SetReplay enables or disables replay mode. In replay mode, EXEC commits are
+// rebuilt by running their command again and ADD commits by copying their
+// sources again instead of applying their layer diff. Other commits, commits
+// without operation record and commits resulting of a squash or a fixup are
+// still applied using their layer diff.
+func (rs *RebaseSession) SetReplay(replay bool) {
+	rs.replay = replay
+}
+
+// Replay returns true if replay mode is enabled.
+func (rs *RebaseSession) Replay() bool {
+	return rs.replay
+}
+
+// replayCommit rebuilds the given commit on builder by executing its
+// operation again. False is returned if commit operation can't be replayed.
+func (rs *RebaseSession) replayCommit(builder *buildah.Builder, commit *RebaseCommit) (bool, error) {
+	// Records of squashed and fixed up commits have no arguments as they
+	// don't describe melded operations.
+	record := commit.Commit.operationRecord()
+	if record == nil || record.Args == nil {
+		return false, nil
+	}
+
+	switch record.CommitOperation() {
+	case ExecCommitOperation:
+		op, err := commit.ExecOperation()
+		if err != nil {
+			return false, err
+		}
+
+		err = runOperation(builder, op, ExecOptions{
+			Stdin:  nil,
+			Stdout: rs.stdout,
+			Stderr: rs.stderr,
+		}, rs.runtime.systemContext())
+		if err != nil {
+			return false, err
+		}
+
+	case AddCommitOperation:
+		op, err := commit.AddOperation()
+		if err != nil {
+			return false, err
+		}
+
+		err = replayAdd(builder, op)
+		if err != nil {
+			return false, err
+		}
+
+	default:
+		return false, nil
+	}
+
+	builder.SetCreatedBy(commit.CreatedBy())
+
+	return true, nil
+}
+
+// replayAdd copies sources of the given ADD operation in builder container.
+func replayAdd(builder *buildah.Builder, op AddOperation) error {
+	for _, src := range op.Sources {
+		srcURL, err := url.Parse(src)
+		if err != nil {
+			return fmt.Errorf("failed to parse sources URL: %w", err)
+		}
+
+		// Remote sources are fetched again by builder
+		if srcURL.Scheme != "" {
+			continue
+		}
+
+		_, err = os.Stat(src)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %v", ErrReplayAddSourceNotFound, src)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat ADD source: %w", err)
+		}
+	}
+
+	options := AddOptions{
+		Chmod:        op.Chmod,
+		Chown:        op.Chown,
+		Message:      "",
+		ReportWriter: nil,
+	}
+	err := builder.Add(op.Dest, false, options.toAddAndCopyOptions(), op.Sources...)
+	if err != nil {
+		return fmt.Errorf("failed to add files to image: %w", err)
+	}
+
+	return nil
+}
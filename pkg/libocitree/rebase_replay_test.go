@@ -0,0 +1,204 @@
+package libocitree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebaseSessionReplay(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	src := filepath.Join(t.TempDir(), "added")
+	require.NoError(t, os.WriteFile(src, []byte("v1"), 0644))
+
+	err = repo.Add("/added", AddOptions{
+		Chmod:        "",
+		Chown:        "",
+		Message:      "add file",
+		ReportWriter: nil,
+	}, src)
+	require.NoError(t, err)
+
+	err = repo.Exec(ExecOptions{
+		Stdin:        nil,
+		Stdout:       nil,
+		Stderr:       nil,
+		Message:      "copy file",
+		ReportWriter: nil,
+	}, "/bin/sh", "-c", "cat /added > /copy")
+	require.NoError(t, err)
+
+	t.Run("Valid", func(t *testing.T) {
+		// Replayed commits use up to date sources
+		require.NoError(t, os.WriteFile(src, []byte("v2"), 0644))
+
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+		session.SetReplay(true)
+
+		err = session.Apply()
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, ExecCommitOperation, commits[0].Operation())
+		require.Equal(t, "copy file", commits[0].Message())
+		require.Equal(t, AddCommitOperation, commits[1].Operation())
+		require.Equal(t, "add file", commits[1].Message())
+
+		require.Equal(t, "v2", readRepoFile(t, repo, "/added"))
+		require.Equal(t, "v2", readRepoFile(t, repo, "/copy"))
+	})
+
+	t.Run("MissingAddSource", func(t *testing.T) {
+		require.NoError(t, os.Remove(src))
+		headID := repo.ID()
+
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+		session.SetReplay(true)
+
+		err = session.Apply()
+		require.ErrorIs(t, err, ErrReplayAddSourceNotFound)
+		require.True(t, repo.RebaseInProgress())
+
+		err = session.Abort()
+		require.NoError(t, err)
+		require.Equal(t, headID, repo.ID())
+	})
+}
+
+func TestRebaseSessionReplayCommitFallback(t *testing.T) {
+	session := &RebaseSession{}
+
+	configRecord, err := newOperationRecord(ConfigCommitOperation, ConfigChanges{Entrypoint: []string{"/bin/app"}})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		commit Commit
+	}{
+		{
+			name:   "NoRecord",
+			commit: newTestCommit(CommitPrefix+`EXEC ["/bin/sh" "-c" "true"]`, "legacy\n"),
+		},
+		{
+			name: "Config",
+			commit: newTestCommit(CommitPrefix+`CONFIG {"entrypoint":["/bin/app"],"cmd":null}`,
+				testHistoryComment(t, "", configRecord)),
+		},
+		{
+			name: "Melded",
+			commit: newTestCommit(CommitPrefix+`EXEC ["/bin/sh" "-c" "true"]`,
+				testHistoryComment(t, "squashed", operationRecordFromCreatedBy(`EXEC ["/bin/sh" "-c" "true"]`))),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Builder isn't used by commits that can't be replayed.
+			replayed, err := session.replayCommit(nil, &RebaseCommit{
+				Commit:     test.commit,
+				index:      0,
+				Choice:     PickRebaseChoice,
+				NewMessage: "",
+				Command:    "",
+			})
+			require.NoError(t, err)
+			require.False(t, replayed)
+		})
+	}
+}
+
+func TestRebaseSessionReplayFallback(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	repo := initTestRepository(t, manager, "localhost/ocitree/replay-fallback", time.Now(), map[string]string{
+		"hello": "world\n",
+	})
+	baseTag, err := reference.LocalTagFromString("base")
+	require.NoError(t, err)
+	require.NoError(t, repo.AddTag(baseTag))
+
+	err = repo.Config(ConfigOptions{
+		Changes: ConfigChanges{
+			Env:        []string{"FOO=bar"},
+			Entrypoint: []string{"/bin/app"},
+			Cmd:        nil,
+			WorkDir:    "",
+			User:       "",
+			Labels:     nil,
+			Expose:     nil,
+		},
+		Message:      "configure app",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+
+	// Import commits have a record without arguments.
+	archivePath := filepath.Join(t.TempDir(), "layer.tar")
+	require.NoError(t, os.WriteFile(archivePath, newTestLayer(t, map[string]string{"imported": "imported\n"}), 0644))
+	err = repo.Import(archivePath, ImportOptions{
+		Dest:         "/",
+		Replace:      false,
+		Message:      "import file",
+		ReportWriter: nil,
+	})
+	require.NoError(t, err)
+
+	src := filepath.Join(t.TempDir(), "added")
+	require.NoError(t, os.WriteFile(src, []byte("v1"), 0644))
+	err = repo.Add("/added", AddOptions{
+		Chmod:        "",
+		Chown:        "",
+		Message:      "add file",
+		ReportWriter: nil,
+	}, src)
+	require.NoError(t, err)
+
+	// ADD commit is replayed using up to date source
+	require.NoError(t, os.WriteFile(src, []byte("v2"), 0644))
+
+	session, err := repo.RebaseSession(reference.NewLocal(repo.Name(), baseTag))
+	require.NoError(t, err)
+	session.SetReplay(true)
+
+	err = session.Apply()
+	require.NoError(t, err)
+
+	commits, err := repo.Commits()
+	require.NoError(t, err)
+	require.Equal(t, AddCommitOperation, commits[0].Operation())
+	require.Equal(t, ImportCommitOperation, commits[1].Operation())
+	require.Equal(t, ConfigCommitOperation, commits[2].Operation())
+
+	require.Equal(t, "v2", readRepoFile(t, repo, "/added"))
+	require.Equal(t, "imported\n", readRepoFile(t, repo, "/imported"))
+
+	data, err := repo.head.Inspect(context.Background(), nil)
+	require.NoError(t, err)
+	require.Contains(t, data.Config.Env, "FOO=bar")
+	require.Equal(t, []string{"/bin/app"}, data.Config.Entrypoint)
+}
@@ -0,0 +1,253 @@
+package libocitree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+const rebaseStateFile = "rebase.json"
+
+var (
+	ErrNoRebaseInProgress   = errors.New("no rebase in progress")
+	ErrRebaseHeadMoved      = errors.New("HEAD moved since rebase started")
+	ErrRebaseCommitNotFound = errors.New("rebase commit not found in repository history")
+)
+
+// rebaseState holds the persisted state of a rebase session.
+type rebaseState struct {
+	OrigHead         string            `json:"origHead"`
+	OldBase          string            `json:"oldBase,omitempty"`
+	Base             string            `json:"base"`
+	Step             int               `json:"step"`
+	ConflictStrategy string            `json:"conflictStrategy"`
+	Replay           bool              `json:"replay,omitempty"`
+	Todo             []rebaseStateLine `json:"todo"`
+}
+
+// rebaseStateLine holds the persisted state of a single RebaseCommit.
+type rebaseStateLine struct {
+	Choice     string `json:"choice"`
+	CommitID   string `json:"commitId,omitempty"`
+	NewMessage string `json:"newMessage,omitempty"`
+	Command    string `json:"command,omitempty"`
+}
+
+func rebaseStatePath(runtime imageRuntime, name reference.Name) (string, error) {
+	dir, err := runtime.repositoryDir(name)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, rebaseStateFile), nil
+}
+
+// RebaseInProgress returns true if a rebase of this repository was started
+// but neither finished nor aborted.
+func (r *Repository) RebaseInProgress() bool {
+	path, err := rebaseStatePath(r.runtime, r.Name())
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// RebaseSessionInProgress returns the RebaseSession in progress. An error is
+// returned if there is no rebase in progress.
+func (r *Repository) RebaseSessionInProgress() (*RebaseSession, error) {
+	path, err := rebaseStatePath(r.runtime, r.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	rawState, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoRebaseInProgress
+		}
+		return nil, fmt.Errorf("failed to read rebase state: %w", err)
+	}
+
+	state := rebaseState{}
+	err = json.Unmarshal(rawState, &state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rebase state: %w", err)
+	}
+
+	return newRebaseSessionFromState(r.runtime, r, state)
+}
+
+func newRebaseSessionFromState(runtime imageRuntime, repo *Repository, state rebaseState) (*RebaseSession, error) {
+	if repo.ID() != state.OrigHead {
+		return nil, ErrRebaseHeadMoved
+	}
+
+	baseID, err := reference.IDFromString(state.Base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rebase base ID: %w", err)
+	}
+	baseImage, err := runtime.lookupImage(reference.NewLocal(repo.Name(), baseID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rebase base: %w", err)
+	}
+
+	commits, err := repo.Commits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve repository commits: %w", err)
+	}
+
+	rebaseCommits := RebaseCommits{
+		commits: make([]*RebaseCommit, len(state.Todo)),
+	}
+	for i, line := range state.Todo {
+		choice := choiceFromString(line.Choice)
+		if choice == UnknownRebaseChoice {
+			return nil, ErrUnknownRebaseChoice
+		}
+
+		if choice == ExecRebaseChoice {
+			rebaseCommits.commits[i] = newExecRebaseCommit(line.Command)
+			continue
+		}
+
+		index := commits.indexOf(line.CommitID)
+		if index == -1 {
+			return nil, fmt.Errorf("%w: %v", ErrRebaseCommitNotFound, line.CommitID)
+		}
+
+		rebaseCommits.commits[i] = &RebaseCommit{
+			Commit:     commits[index],
+			index:      index,
+			Choice:     choice,
+			NewMessage: line.NewMessage,
+		}
+	}
+
+	return &RebaseSession{
+		baseImage:        baseImage,
+		repository:       repo,
+		commits:          rebaseCommits,
+		runtime:          runtime,
+		step:             state.Step,
+		oldBaseID:        state.OldBase,
+		conflictStrategy: RebaseConflictStrategyFromString(state.ConflictStrategy),
+		stepStrategies:   make(map[int]RebaseConflictStrategy),
+		baseChangesCache: nil,
+		replay:           state.Replay,
+		stdout:           nil,
+		stderr:           nil,
+	}, nil
+}
+
+// saveState persists rebase session state.
+func (rs *RebaseSession) saveState() error {
+	state := rebaseState{
+		OrigHead:         rs.repository.ID(),
+		OldBase:          rs.oldBaseID,
+		Base:             rs.baseImage.ID(),
+		Step:             rs.step,
+		ConflictStrategy: rs.conflictStrategy.String(),
+		Replay:           rs.replay,
+		Todo:             make([]rebaseStateLine, rs.commits.Len()),
+	}
+
+	for i := 0; i < rs.commits.Len(); i++ {
+		commit := rs.commits.Get(i)
+		state.Todo[i] = rebaseStateLine{
+			Choice:     commit.Choice.String(),
+			CommitID:   commit.ID(),
+			NewMessage: commit.NewMessage,
+			Command:    commit.Command,
+		}
+	}
+
+	rawState, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize rebase state: %w", err)
+	}
+
+	path, err := rebaseStatePath(rs.runtime, rs.repository.Name())
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, rawState, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write rebase state: %w", err)
+	}
+
+	return nil
+}
+
+// removeState removes persisted rebase session state.
+func (rs *RebaseSession) removeState() error {
+	path, err := rebaseStatePath(rs.runtime, rs.repository.Name())
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+
+	return nil
+}
+
+// Step returns the index of the rebase commit currently applied.
+func (rs *RebaseSession) Step() int {
+	return rs.step
+}
+
+// Continue resumes an interrupted rebase by retrying the current step.
+// Conflicts that interrupted the rebase can be resolved by setting a strategy
+// for the current step using SetStepConflictStrategy beforehand.
+func (rs *RebaseSession) Continue() error {
+	return rs.resume()
+}
+
+// Skip resumes an interrupted rebase after dropping the current rebase commit
+// along with the squash and fixup commits melded into it.
+func (rs *RebaseSession) Skip() error {
+	if rs.step < rs.commits.Len() {
+		rs.commits.Get(rs.step).Choice = DropRebaseChoice
+
+		for i := rs.step + 1; i < rs.commits.Len(); i++ {
+			commit := rs.commits.Get(i)
+			if commit.Choice != SquashRebaseChoice && commit.Choice != FixupRebaseChoice &&
+				commit.Choice != DropRebaseChoice {
+				break
+			}
+			commit.Choice = DropRebaseChoice
+		}
+	}
+
+	err := rs.saveState()
+	if err != nil {
+		return err
+	}
+
+	return rs.resume()
+}
+
+// Abort aborts an interrupted rebase, HEAD is restored to its original
+// position and REBASE_HEAD is removed.
+func (rs *RebaseSession) Abort() error {
+	origHead, err := reference.IDFromString(rs.repository.ID())
+	if err != nil {
+		return fmt.Errorf("failed to parse original HEAD ID: %w", err)
+	}
+
+	err = rs.repository.checkout(reference.NewLocal(rs.repository.Name(), origHead), RebaseReflogOperation, "abort")
+	if err != nil {
+		return fmt.Errorf("failed to restore original HEAD: %w", err)
+	}
+
+	return rs.cleanup()
+}
@@ -0,0 +1,162 @@
+package libocitree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+// setupInterruptedRebaseTest clones alpine, creates two commits and starts
+// a rebase that fails on an exec line between them.
+func setupInterruptedRebaseTest(t *testing.T) (*Manager, func(), *Repository, string) {
+	manager, cleanup := newTestManager(t)
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	for _, cmd := range []string{"touch /commit1", "touch /commit2"} {
+		err = repo.Exec(ExecOptions{
+			Stdin:        nil,
+			Stdout:       nil,
+			Stderr:       nil,
+			Message:      cmd,
+			ReportWriter: nil,
+		}, "/bin/sh", "-c", cmd)
+		require.NoError(t, err)
+	}
+	origHead := repo.ID()
+
+	session, err := repo.RebaseSession(ref)
+	require.NoError(t, err)
+
+	commits := session.Commits()
+	err = commits.ParseChoices(commits.Get(0).Choice.String() + " " + commits.Get(0).ID() + "\n" +
+		"exec touch /exec1 && exit 1\n" +
+		commits.Get(1).Choice.String() + " " + commits.Get(1).ID())
+	require.NoError(t, err)
+
+	err = session.Apply()
+	require.Error(t, err)
+
+	// Rebase is in progress
+	require.True(t, repo.RebaseInProgress())
+	require.Equal(t, origHead, repo.ID(), "HEAD moved during rebase")
+
+	return manager, cleanup, repo, origHead
+}
+
+func requireRebaseHeadRemoved(t *testing.T, manager *Manager, repo *Repository) {
+	rebaseHeadExist, err := manager.rt.Exists(reference.NewLocal(repo.Name(), reference.RebaseHeadTag).String())
+	require.NoError(t, err)
+	require.False(t, rebaseHeadExist, "REBASE_HEAD reference wasn't removed")
+	require.False(t, repo.RebaseInProgress())
+}
+
+func TestRebaseSessionInProgress(t *testing.T) {
+	manager, cleanup, repo, _ := setupInterruptedRebaseTest(t)
+	defer cleanup()
+
+	// Other commands refuse to run
+	err := repo.Exec(ExecOptions{}, "/bin/true")
+	require.Equal(t, ErrRebaseInProgress, err)
+	err = repo.Checkout(reference.LocalFromName(repo.Name()))
+	require.Equal(t, ErrRebaseInProgress, err)
+	_, err = repo.RebaseSession(reference.LocalFromName(repo.Name()))
+	require.Equal(t, ErrRebaseInProgress, err)
+
+	// Restore session
+	session, err := repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+	require.Equal(t, 1, session.Step())
+	require.Equal(t, 3, session.Commits().Len())
+	require.Equal(t, ExecRebaseChoice, session.Commits().Get(1).Choice)
+	require.Equal(t, "touch /exec1 && exit 1", session.Commits().Get(1).Command)
+
+	err = session.Abort()
+	require.NoError(t, err)
+	requireRebaseHeadRemoved(t, manager, repo)
+
+	// No rebase in progress anymore
+	_, err = repo.RebaseSessionInProgress()
+	require.Equal(t, ErrNoRebaseInProgress, err)
+}
+
+func TestRebaseSessionAbort(t *testing.T) {
+	manager, cleanup, repo, origHead := setupInterruptedRebaseTest(t)
+	defer cleanup()
+
+	session, err := repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+
+	err = session.Abort()
+	require.NoError(t, err)
+	requireRebaseHeadRemoved(t, manager, repo)
+
+	err = repo.ReloadHead()
+	require.NoError(t, err)
+	require.Equal(t, origHead, repo.ID(), "original HEAD wasn't restored")
+}
+
+func TestRebaseSessionContinue(t *testing.T) {
+	manager, cleanup, repo, _ := setupInterruptedRebaseTest(t)
+	defer cleanup()
+
+	session, err := repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+
+	// Fix failing command and continue
+	session.Commits().Get(session.Step()).Command = "touch /exec1"
+	err = session.Continue()
+	require.NoError(t, err)
+	requireRebaseHeadRemoved(t, manager, repo)
+
+	err = repo.ReloadHead()
+	require.NoError(t, err)
+
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+	defer repo.Unmount()
+
+	require.FileExists(t, filepath.Join(mountpoint, "commit1"))
+	require.FileExists(t, filepath.Join(mountpoint, "exec1"))
+	require.FileExists(t, filepath.Join(mountpoint, "commit2"))
+}
+
+func TestRebaseSessionSkip(t *testing.T) {
+	manager, cleanup, repo, _ := setupInterruptedRebaseTest(t)
+	defer cleanup()
+
+	session, err := repo.RebaseSessionInProgress()
+	require.NoError(t, err)
+
+	err = session.Skip()
+	require.NoError(t, err)
+	requireRebaseHeadRemoved(t, manager, repo)
+
+	err = repo.ReloadHead()
+	require.NoError(t, err)
+
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+	defer repo.Unmount()
+
+	require.FileExists(t, filepath.Join(mountpoint, "commit1"))
+	require.NoFileExists(t, filepath.Join(mountpoint, "exec1"))
+	require.FileExists(t, filepath.Join(mountpoint, "commit2"))
+}
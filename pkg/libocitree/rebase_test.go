@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/negrel/ocitree/pkg/reference"
 	"github.com/stretchr/testify/require"
@@ -166,7 +167,7 @@ func TestParseRebaseChoices(t *testing.T) {
 		err = commits.ParseChoices(strings.Join(splitted, "\n"))
 		require.Error(t, err)
 		require.Truef(t,
-			regexp.MustCompile(`^failed to parse line "[^"]+": invalid rebase commit id$`).
+			regexp.MustCompile(`^failed to parse line \d+ "[^"]+": invalid rebase commit id$`).
 				MatchString(err.Error()),
 			"error %q doesn't match expected format",
 			err.Error(),
@@ -188,7 +189,7 @@ func TestParseRebaseChoices(t *testing.T) {
 		err = commits.ParseChoices(strings.Join(splitted, "\n"))
 		require.Error(t, err)
 		require.Truef(t,
-			regexp.MustCompile(`^failed to parse line "[^"]+": rebase commit line already parsed$`).
+			regexp.MustCompile(`^failed to parse line \d+ "[^"]+": rebase commit line already parsed$`).
 				MatchString(err.Error()),
 			"error %q doesn't match expected format",
 			err.Error(),
@@ -296,3 +297,317 @@ func TestRebaseSession(t *testing.T) {
 
 	repo.Unmount()
 }
+
+func TestRebaseSessionReword(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	err = repo.Exec(ExecOptions{
+		Stdin:        nil,
+		Stdout:       nil,
+		Stderr:       nil,
+		Message:      "commit 1",
+		ReportWriter: nil,
+	}, "/bin/sh", "-c", "touch /commit1")
+	require.NoError(t, err)
+
+	// Create a rebase session
+	session, err := repo.RebaseSession(ref)
+	require.NoError(t, err)
+
+	commits := session.Commits()
+	require.Equal(t, 1, commits.Len(), "number of commits part of rebase session")
+
+	// Parse reword choice
+	err = commits.ParseChoices(setRebaseCommitChoice(commits.String(), RewordRebaseChoice))
+	require.NoError(t, err)
+	require.Equal(t, RewordRebaseChoice, commits.Get(0).Choice)
+
+	// Reword commit 1
+	newMessage := randomCommitMessage()
+	commits.Get(0).NewMessage = newMessage
+
+	err = session.Apply()
+	require.NoError(t, err)
+
+	err = repo.ReloadHead()
+	require.NoError(t, err)
+
+	repoCommits, err := repo.Commits()
+	require.NoError(t, err)
+	require.Equal(t, newMessage, repoCommits[0].Message(), "commit wasn't reworded")
+	require.Equal(t, ExecCommitOperation, repoCommits[0].Operation())
+
+	// Ensure layer was picked
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(mountpoint, "commit1"))
+
+	repo.Unmount()
+}
+
+func TestRebaseSessionSquash(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	baseCommits, err := repo.Commits()
+	require.NoError(t, err)
+
+	for i, cmd := range []string{
+		"touch /commit1 /commit1.dup",
+		// Delete a file added by a previous commit to ensure whiteouts are
+		// handled.
+		"rm -f /commit1.dup",
+		"touch /commit3",
+	} {
+		err = repo.Exec(ExecOptions{
+			Stdin:        nil,
+			Stdout:       nil,
+			Stderr:       nil,
+			Message:      fmt.Sprintf("commit %d", i+1),
+			ReportWriter: nil,
+		}, "/bin/sh", "-c", cmd)
+		require.NoError(t, err)
+	}
+
+	t.Run("WithoutPreviousCommit", func(t *testing.T) {
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+
+		commits := session.Commits()
+		commits.Get(0).Choice = FixupRebaseChoice
+
+		err = session.Apply()
+		require.Error(t, err)
+		require.ErrorIs(t, err, ErrNoPreviousRebaseCommit)
+	})
+
+	t.Run("SquashAndFixup", func(t *testing.T) {
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+
+		commits := session.Commits()
+		require.Equal(t, 3, commits.Len(), "number of commits part of rebase session")
+
+		// pick commit 1
+		commits.Get(0).Choice = PickRebaseChoice
+		// fixup commit 2
+		commits.Get(1).Choice = FixupRebaseChoice
+		// squash commit 3
+		commits.Get(2).Choice = SquashRebaseChoice
+		commits.Get(2).NewMessage = "commit 1 & 3"
+
+		err = session.Apply()
+		require.NoError(t, err)
+
+		err = repo.ReloadHead()
+		require.NoError(t, err)
+
+		// Commits were melded into a single one
+		repoCommits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Len(t, repoCommits, len(baseCommits)+1)
+		require.Equal(t, "commit 1 & 3", repoCommits[0].Message())
+
+		// Record of melded commit has no arguments as it doesn't describe
+		// the melded EXEC operations.
+		record, err := repoCommits[0].OperationRecord()
+		require.NoError(t, err)
+		require.Equal(t, ExecCommitOperation, record.CommitOperation())
+		require.Nil(t, record.Args)
+		_, err = repoCommits[0].ExecOperation()
+		require.Error(t, err)
+
+		mountpoint, err := repo.Mount()
+		require.NoError(t, err)
+
+		require.FileExists(t, filepath.Join(mountpoint, "commit1"))
+		require.NoFileExists(t, filepath.Join(mountpoint, "commit1.dup"))
+		require.FileExists(t, filepath.Join(mountpoint, "commit3"))
+
+		repo.Unmount()
+	})
+}
+
+func TestRebaseSessionSquashOperationRecord(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	repo := initTestRepository(t, manager, "localhost/ocitree/squash", time.Now(), map[string]string{
+		"hello": "world\n",
+	})
+	initID := repo.ID()
+	baseTag, err := reference.LocalTagFromString("base")
+	require.NoError(t, err)
+	require.NoError(t, repo.AddTag(baseTag))
+
+	for i := 1; i <= 2; i++ {
+		src := filepath.Join(t.TempDir(), fmt.Sprintf("commit%d", i))
+		require.NoError(t, os.WriteFile(src, []byte(src), 0644))
+
+		err = repo.Add("/", AddOptions{
+			Chmod:        "",
+			Chown:        "",
+			Message:      fmt.Sprintf("commit %d", i),
+			ReportWriter: nil,
+		}, src)
+		require.NoError(t, err)
+	}
+
+	session, err := repo.RebaseSession(reference.NewLocal(repo.Name(), baseTag))
+	require.NoError(t, err)
+
+	commits := session.Commits()
+	require.Equal(t, 2, commits.Len())
+	commits.Get(1).Choice = SquashRebaseChoice
+	commits.Get(1).NewMessage = "commit 1 & 2"
+
+	err = session.Apply()
+	require.NoError(t, err)
+
+	repoCommits, err := repo.Commits()
+	require.NoError(t, err)
+	require.Equal(t, "commit 1 & 2", repoCommits[0].Message())
+	require.Equal(t, initID, repoCommits[0].Parent().ID())
+
+	// Squashed commit record doesn't claim a single ADD operation.
+	record, err := repoCommits[0].OperationRecord()
+	require.NoError(t, err)
+	require.Equal(t, AddCommitOperation, record.CommitOperation())
+	require.Nil(t, record.Args)
+	_, err = repoCommits[0].AddOperation()
+	require.Error(t, err)
+}
+
+func TestRebaseSessionExec(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	err = repo.Exec(ExecOptions{
+		Stdin:        nil,
+		Stdout:       nil,
+		Stderr:       nil,
+		Message:      "commit 1",
+		ReportWriter: nil,
+	}, "/bin/sh", "-c", "touch /commit1")
+	require.NoError(t, err)
+
+	t.Run("MissingCommand", func(t *testing.T) {
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+
+		commits := session.Commits()
+		for _, line := range []string{"exec  ", "x"} {
+			err = commits.ParseChoices(commits.String() + "\n" + line)
+			require.Error(t, err)
+			require.Truef(t,
+				regexp.MustCompile(`^failed to parse line 2 "[^"]+": missing exec command$`).
+					MatchString(err.Error()),
+				"error %q doesn't match expected format",
+				err.Error(),
+			)
+		}
+	})
+
+	t.Run("FailingCommand", func(t *testing.T) {
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+
+		commits := session.Commits()
+		err = commits.ParseChoices(commits.String() + "\nx exit 1")
+		require.NoError(t, err)
+
+		err = session.Apply()
+		require.Error(t, err)
+
+		err = session.Abort()
+		require.NoError(t, err)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		session, err := repo.RebaseSession(ref)
+		require.NoError(t, err)
+
+		commits := session.Commits()
+		choices := "exec touch /exec1\n" + commits.String() + "\nexec rm /commit1 && touch /exec2"
+		err = commits.ParseChoices(choices)
+		require.NoError(t, err)
+		require.Equal(t, 3, commits.Len())
+		require.Equal(t, choices, commits.String())
+
+		require.Equal(t, ExecRebaseChoice, commits.Get(0).Choice)
+		require.Equal(t, "touch /exec1", commits.Get(0).Command)
+		require.Equal(t, PickRebaseChoice, commits.Get(1).Choice)
+		require.Equal(t, ExecRebaseChoice, commits.Get(2).Choice)
+		require.Equal(t, "rm /commit1 && touch /exec2", commits.Get(2).Command)
+
+		err = session.Apply()
+		require.NoError(t, err)
+
+		err = repo.ReloadHead()
+		require.NoError(t, err)
+
+		repoCommits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, ExecCommitOperation, repoCommits[0].Operation())
+		require.Equal(t, `/bin/sh -c #(ocitree) EXEC ["/bin/sh" "-c" "rm /commit1 && touch /exec2"]`, repoCommits[0].CreatedBy())
+		require.Equal(t, "commit 1", repoCommits[1].Message())
+
+		mountpoint, err := repo.Mount()
+		require.NoError(t, err)
+
+		require.FileExists(t, filepath.Join(mountpoint, "exec1"))
+		require.FileExists(t, filepath.Join(mountpoint, "exec2"))
+		require.NoFileExists(t, filepath.Join(mountpoint, "commit1"))
+
+		repo.Unmount()
+	})
+}
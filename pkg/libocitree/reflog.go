@@ -0,0 +1,258 @@
+package libocitree
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+const reflogFile = "reflog"
+
+var (
+	ErrReflogEntryNotFound = errors.New("reflog entry not found")
+)
+
+// ReflogOperation define the operation that moved HEAD.
+type ReflogOperation uint
+
+const (
+	UnknownReflogOperation ReflogOperation = iota
+	CloneReflogOperation
+	CheckoutReflogOperation
+	CommitReflogOperation
+	RebaseReflogOperation
+	ResetReflogOperation
+	RevertReflogOperation
+	CherryPickReflogOperation
+	FlattenReflogOperation
+	InitReflogOperation
+	ImportReflogOperation
+	AmReflogOperation
+)
+
+// ReflogOperationFromString parses the given reflog operation.
+func ReflogOperationFromString(str string) ReflogOperation {
+	switch strings.ToLower(str) {
+	case "clone":
+		return CloneReflogOperation
+	case "checkout":
+		return CheckoutReflogOperation
+	case "commit":
+		return CommitReflogOperation
+	case "rebase":
+		return RebaseReflogOperation
+	case "reset":
+		return ResetReflogOperation
+	case "revert":
+		return RevertReflogOperation
+	case "cherry-pick":
+		return CherryPickReflogOperation
+	case "flatten":
+		return FlattenReflogOperation
+	case "init":
+		return InitReflogOperation
+	case "import":
+		return ImportReflogOperation
+	case "am":
+		return AmReflogOperation
+	default:
+		return UnknownReflogOperation
+	}
+}
+
+// String implements fmt.Stringer.
+func (ro ReflogOperation) String() string {
+	switch ro {
+	case CloneReflogOperation:
+		return "clone"
+	case CheckoutReflogOperation:
+		return "checkout"
+	case CommitReflogOperation:
+		return "commit"
+	case RebaseReflogOperation:
+		return "rebase"
+	case ResetReflogOperation:
+		return "reset"
+	case RevertReflogOperation:
+		return "revert"
+	case CherryPickReflogOperation:
+		return "cherry-pick"
+	case FlattenReflogOperation:
+		return "flatten"
+	case InitReflogOperation:
+		return "init"
+	case ImportReflogOperation:
+		return "import"
+	case AmReflogOperation:
+		return "am"
+	default:
+		return "unknown"
+	}
+}
+
+// ReflogEntry define a single movement of HEAD.
+type ReflogEntry struct {
+	// OldID is the ID of HEAD before the movement, it is empty for the first
+	// entry of a cloned or initialized repository.
+	OldID     string
+	NewID     string
+	Operation ReflogOperation
+	Message   string
+	Timestamp time.Time
+}
+
+// reflogLine holds the persisted state of a single ReflogEntry.
+type reflogLine struct {
+	OldID     string    `json:"oldId,omitempty"`
+	NewID     string    `json:"newId"`
+	Operation string    `json:"operation"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func reflogPath(runtime imageRuntime, name reference.Name) (string, error) {
+	dir, err := runtime.repositoryDir(name)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, reflogFile), nil
+}
+
+// appendReflog appends the given entry to the reflog of the repository with
+// the given name.
+func appendReflog(runtime imageRuntime, name reference.Name, entry ReflogEntry) error {
+	path, err := reflogPath(runtime, name)
+	if err != nil {
+		return err
+	}
+
+	rawLine, err := json.Marshal(reflogLine{
+		OldID:     entry.OldID,
+		NewID:     entry.NewID,
+		Operation: entry.Operation.String(),
+		Message:   entry.Message,
+		Timestamp: entry.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize reflog entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open reflog: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(rawLine, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write reflog entry: %w", err)
+	}
+
+	return nil
+}
+
+// readReflog returns reflog entries of the repository with the given name,
+// ordered from newer to older.
+func readReflog(runtime imageRuntime, name reference.Name) ([]ReflogEntry, error) {
+	path, err := reflogPath(runtime, name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []ReflogEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open reflog: %w", err)
+	}
+	defer f.Close()
+
+	entries := make([]ReflogEntry, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		line := reflogLine{}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reflog entry %d: %w", len(entries), err)
+		}
+
+		entries = append(entries, ReflogEntry{
+			OldID:     line.OldID,
+			NewID:     line.NewID,
+			Operation: ReflogOperationFromString(line.Operation),
+			Message:   line.Message,
+			Timestamp: line.Timestamp,
+		})
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reflog: %w", err)
+	}
+
+	// Newer entries first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, nil
+}
+
+// resolveReflogSelector returns the ID of HEAD at the position selected by
+// the given selector.
+func resolveReflogSelector(entries []ReflogEntry, selector *reference.ReflogSelector) (string, error) {
+	if index, isIndex := selector.Index(); isIndex {
+		if index >= uint(len(entries)) {
+			return "", fmt.Errorf("%w: only %d entries", ErrReflogEntryNotFound, len(entries))
+		}
+
+		return entries[index].NewID, nil
+	}
+
+	date, _ := selector.Date()
+	for _, entry := range entries {
+		if !entry.Timestamp.After(date) {
+			return entry.NewID, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: reflog doesn't go back to %v", ErrReflogEntryNotFound, date.Format(time.RFC3339))
+}
+
+// Reflog returns the movements of HEAD, ordered from newer to older.
+func (r *Repository) Reflog() ([]ReflogEntry, error) {
+	return readReflog(r.runtime, r.Name())
+}
+
+// logHeadMove records HEAD movement from oldID to current HEAD in the
+// reflog. Nothing is recorded if HEAD didn't move.
+func (r *Repository) logHeadMove(oldID string, operation ReflogOperation, message string) error {
+	if oldID == r.ID() {
+		return nil
+	}
+
+	err := appendReflog(r.runtime, r.Name(), ReflogEntry{
+		OldID:     oldID,
+		NewID:     r.ID(),
+		Operation: operation,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update reflog: %w", err)
+	}
+
+	return nil
+}
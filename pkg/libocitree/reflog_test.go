@@ -0,0 +1,106 @@
+package libocitree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveReflogSelector(t *testing.T) {
+	now := time.Now()
+	entries := []ReflogEntry{
+		{OldID: "b", NewID: "c", Operation: ResetReflogOperation, Message: "", Timestamp: now},
+		{OldID: "a", NewID: "b", Operation: CommitReflogOperation, Message: "", Timestamp: now.Add(-2 * time.Hour)},
+		{OldID: "", NewID: "a", Operation: CloneReflogOperation, Message: "", Timestamp: now.Add(-48 * time.Hour)},
+	}
+
+	for _, test := range []struct {
+		name          string
+		selector      string
+		expectedID    string
+		expectedError error
+	}{
+		{name: "Index/Head", selector: "0", expectedID: "c"},
+		{name: "Index/Last", selector: "2", expectedID: "a"},
+		{name: "Index/OutOfBounds", selector: "3", expectedError: ErrReflogEntryNotFound},
+		{name: "Date/Now", selector: "now", expectedID: "c"},
+		{name: "Date/HourAgo", selector: "1.hour.ago", expectedID: "b"},
+		{name: "Date/Yesterday", selector: "yesterday", expectedID: "a"},
+		{name: "Date/TooOld", selector: "1.week.ago", expectedError: ErrReflogEntryNotFound},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := reference.ReflogSelectorFromString(test.selector)
+			require.NoError(t, err)
+
+			id, err := resolveReflogSelector(entries, &selector)
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedID, id)
+		})
+	}
+}
+
+func TestRepositoryReflog(t *testing.T) {
+	manager, cleanup, repo, resetRef := setupResetTest(t)
+	defer cleanup()
+
+	origHead := repo.ID()
+
+	_, err := repo.Reset(resetRef, HardResetMode)
+	require.NoError(t, err)
+
+	entries, err := repo.Reflog()
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+
+	operations := make([]ReflogOperation, len(entries))
+	for i, entry := range entries {
+		operations[i] = entry.Operation
+	}
+	require.Equal(t, []ReflogOperation{
+		ResetReflogOperation,
+		CommitReflogOperation,
+		CommitReflogOperation,
+		CommitReflogOperation,
+		CloneReflogOperation,
+	}, operations)
+
+	require.Equal(t, origHead, entries[0].OldID)
+	require.Equal(t, repo.ID(), entries[0].NewID)
+	require.Equal(t, "commit 3", entries[1].Message)
+	require.Equal(t, "", entries[4].OldID)
+
+	// Entries are chained
+	for i := 0; i < len(entries)-1; i++ {
+		require.Equal(t, entries[i+1].NewID, entries[i].OldID)
+	}
+
+	t.Run("ResolveRelativeReference", func(t *testing.T) {
+		relRef, err := reference.RelativeFromString(repo.Name().String() + "@{1}")
+		require.NoError(t, err)
+
+		ref, err := manager.ResolveRelativeReference(relRef)
+		require.NoError(t, err)
+		require.Equal(t, reference.IdPrefix+origHead, ref.IdOrTag())
+
+		relRef, err = reference.RelativeFromString(repo.Name().String() + "@{1}~1")
+		require.NoError(t, err)
+
+		ref, err = manager.ResolveRelativeReference(relRef)
+		require.NoError(t, err)
+		require.Equal(t, reference.IdPrefix+entries[1].OldID, ref.IdOrTag())
+	})
+
+	t.Run("ResolveRelativeReference/OutOfBounds", func(t *testing.T) {
+		relRef, err := reference.RelativeFromString(repo.Name().String() + "@{5}")
+		require.NoError(t, err)
+
+		_, err = manager.ResolveRelativeReference(relRef)
+		require.ErrorIs(t, err, ErrReflogEntryNotFound)
+	})
+}
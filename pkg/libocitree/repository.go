@@ -10,6 +10,7 @@ import (
 	"github.com/containers/common/libimage"
 	dockerref "github.com/containers/image/v5/docker/reference"
 	"github.com/containers/image/v5/types"
+	"github.com/containers/storage/pkg/archive"
 	"github.com/negrel/ocitree/pkg/reference"
 	"github.com/sirupsen/logrus"
 )
@@ -17,6 +18,7 @@ import (
 var (
 	ErrRepositoryInvalidNoName  = errors.New("invalid repository, no valid name")
 	ErrImageNotPartOfRepository = errors.New("image is not part of repository")
+	ErrRebaseInProgress         = errors.New("a rebase is in progress")
 )
 
 type imageRuntime interface {
@@ -26,7 +28,16 @@ type imageRuntime interface {
 	storageReference(reference.Reference) types.ImageReference
 	systemContext() *types.SystemContext
 	ResolveRelativeReference(reference.Relative) (reference.Reference, error)
-	diff(from, to *Commit) (io.ReadCloser, error)
+	diff(fromImageID, toImageID string) (io.ReadCloser, error)
+	repositoryDir(reference.Name) (string, error)
+	changes(fromImageID, toImageID string) ([]archive.Change, error)
+	newWorkingContainer(reference.Reference) (*buildah.Builder, error)
+	workingContainer(reference.Name) (*buildah.Builder, error)
+	setWorkingContainer(reference.Name, *buildah.Builder) error
+	containerChanges(containerID string) ([]archive.Change, error)
+	containerDiff(containerID string) (io.ReadCloser, error)
+	mountImage(imageID string) (string, error)
+	unmountImage(imageID string) error
 }
 
 // Repository is an object holding the history of a rootfs (OCI/Docker image).
@@ -161,7 +172,12 @@ func (r *Repository) RemoveTag(tag reference.Tag) error {
 func (r *Repository) removeLocalTag(tag reference.Tag) error {
 	ref := reference.NewLocal(r.HeadRef().Name(), reference.LocalTagFromTag(tag))
 
-	return r.head.Untag(ref.String())
+	img, err := r.runtime.lookupImage(ref)
+	if err != nil {
+		return err
+	}
+
+	return img.Untag(ref.String())
 }
 
 // Commits returns the commits history of this repository.
@@ -175,14 +191,23 @@ func (r *Repository) Commits() (Commits, error) {
 	return newCommits(history), nil
 }
 
-// Mount mounts the repository and returns the mountpoint.
-func (r *Repository) Mount() (string, error) {
-	return r.head.Mount(context.Background(), []string{}, "")
-}
+// Commit returns the commit associated to the given reference.
+func (r *Repository) Commit(ref reference.Reference) (*Commit, error) {
+	img, err := r.runtime.lookupImage(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup commit reference: %w", err)
+	}
+
+	history, err := img.History(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve history from image: %w", err)
+	}
+	if len(history) == 0 {
+		return nil, ErrCommitHasNoImageAssociated
+	}
 
-// Unmount unmount the repository.
-func (r *Repository) Unmount() error {
-	return r.head.Unmount(true)
+	commits := newCommits(history)
+	return &commits[0], nil
 }
 
 func findRepoName(names []dockerref.NamedTagged) string {
@@ -212,7 +237,22 @@ func (r *Repository) containsImage(img *libimage.Image) {
 }
 
 // Checkout to commit with the given Identifier.
+// An error is returned if a rebase is in progress.
 func (r *Repository) Checkout(ref reference.Reference) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
+	return r.checkout(ref, CheckoutReflogOperation, ref.String())
+}
+
+// checkout moves HEAD to the given reference and records the movement in the
+// reflog.
+func (r *Repository) checkout(ref reference.Reference, operation ReflogOperation, message string) error {
 	img, err := r.runtime.lookupImage(ref)
 	if err != nil {
 		return fmt.Errorf("failed to lookup checkout reference: %w", err)
@@ -237,9 +277,10 @@ func (r *Repository) Checkout(ref reference.Reference) error {
 				}
 
 				// Move head
+				oldID := r.ID()
 				r.head = img
 
-				return nil
+				return r.logHeadMove(oldID, operation, message)
 			}
 		} else {
 			logrus.Debugf("skipping %v because reference is not named", ref)
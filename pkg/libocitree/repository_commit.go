@@ -30,11 +30,18 @@ var (
 type CommitOptions struct {
 	CreatedBy string
 	Message   string
+	// Squash squashes the whole builder rootfs into a single layer and
+	// discards history.
+	Squash bool
+	// Record is the operation record stored alongside message. If nil, a
+	// record without arguments is derived from CreatedBy.
+	Record *OperationRecord
 
 	ReportWriter io.Writer
 }
 
-func (r *Repository) commit(builder *buildah.Builder, options CommitOptions) error {
+func (r *Repository) commit(builder *buildah.Builder, operation ReflogOperation, options CommitOptions) error {
+	oldID := r.ID()
 	sref := r.runtime.storageReference(r.headRef)
 	err := commit(builder, options, sref, r.runtime.systemContext())
 	if err != nil {
@@ -46,7 +53,12 @@ func (r *Repository) commit(builder *buildah.Builder, options CommitOptions) err
 		return fmt.Errorf("failed to reload repository's HEAD after commit: %w", err)
 	}
 
-	return nil
+	message := messageSubject(options.Message)
+	if message == "" {
+		message = options.CreatedBy
+	}
+
+	return r.logHeadMove(oldID, operation, message)
 }
 
 // AddOptions holds option to Manager.Add method.
@@ -82,6 +94,14 @@ func (ao *AddOptions) toAddAndCopyOptions() buildah.AddAndCopyOptions {
 
 // Add commits the given source files to HEAD.
 func (r *Repository) Add(dest string, options AddOptions, sources ...string) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
 	for i, src := range sources {
 		srcURL, err := url.Parse(src)
 		if err != nil {
@@ -112,10 +132,21 @@ func (r *Repository) Add(dest string, options AddOptions, sources ...string) err
 
 	createdBy := fmt.Sprintf("%v --chown=%q --chmod=%q %v %v", AddCommitOperation,
 		options.Chown, options.Chmod, stringList(sources), dest)
+	record, err := newOperationRecord(AddCommitOperation, AddOperation{
+		Sources: sources,
+		Dest:    dest,
+		Chown:   options.Chown,
+		Chmod:   options.Chmod,
+	})
+	if err != nil {
+		return err
+	}
 
-	return r.commit(builder, CommitOptions{
+	return r.commit(builder, CommitReflogOperation, CommitOptions{
 		CreatedBy:    createdBy,
 		Message:      options.Message,
+		Squash:       false,
+		Record:       record,
 		ReportWriter: options.ReportWriter,
 	})
 }
@@ -152,6 +183,14 @@ type ExecOptions struct {
 }
 
 func (r *Repository) Exec(options ExecOptions, cmd string, args ...string) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
 	builder, err := r.runtime.repoBuilder(r.headRef, nil)
 	if err != nil {
 		return err
@@ -161,7 +200,47 @@ func (r *Repository) Exec(options ExecOptions, cmd string, args ...string) error
 	command := make([]string, 0, len(args)+1)
 	command = append(command, cmd)
 	command = append(command, args...)
-	err = builder.Run(command, buildah.RunOptions{
+	err = run(builder, command, options, r.runtime.systemContext())
+	if err != nil {
+		return err
+	}
+
+	record, err := newExecOperationRecord(command)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(builder, CommitReflogOperation, CommitOptions{
+		CreatedBy:    ExecCommitOperation.String() + " " + stringList(command).String(),
+		Message:      options.Message,
+		Squash:       false,
+		Record:       record,
+		ReportWriter: options.ReportWriter,
+	})
+}
+
+// execUser is the user commands are run as.
+const execUser = "root"
+
+// run runs the given command in builder container as execUser.
+func run(builder *buildah.Builder, command []string, options ExecOptions, systemContext *types.SystemContext) error {
+	return runOperation(builder, ExecOperation{
+		Argv: command,
+		Env:  nil,
+		User: execUser,
+	}, options, systemContext)
+}
+
+// runOperation runs command of the given EXEC operation in builder container
+// with its environment and user. Commands are run as execUser if operation
+// has no user.
+func runOperation(builder *buildah.Builder, op ExecOperation, options ExecOptions, systemContext *types.SystemContext) error {
+	user := op.User
+	if user == "" {
+		user = execUser
+	}
+
+	err := builder.Run(op.Argv, buildah.RunOptions{
 		Logger:           logrus.StandardLogger(),
 		Hostname:         "",
 		Isolation:        define.IsolationChroot,
@@ -170,8 +249,8 @@ func (r *Repository) Exec(options ExecOptions, cmd string, args ...string) error
 		NoHosts:          false,
 		NoPivot:          false,
 		Mounts:           nil,
-		Env:              nil,
-		User:             "root",
+		Env:              op.Env,
+		User:             user,
 		WorkingDir:       "",
 		ContextDir:       "",
 		Shell:            "",
@@ -207,18 +286,14 @@ func (r *Repository) Exec(options ExecOptions, cmd string, args ...string) error
 		RunMounts:           nil,
 		StageMountPoints:    nil,
 		ExternalImageMounts: nil,
-		SystemContext:       r.runtime.systemContext(),
+		SystemContext:       systemContext,
 		CgroupManager:       "",
 	})
 	if err != nil {
 		return fmt.Errorf("failed to execute command: %w", err)
 	}
 
-	return r.commit(builder, CommitOptions{
-		CreatedBy:    ExecCommitOperation.String() + " " + stringList(command).String(),
-		Message:      options.Message,
-		ReportWriter: options.ReportWriter,
-	})
+	return nil
 }
 
 // RebaseSession starts and returns a new RebaseSession with the given tag as base reference.
@@ -234,6 +309,11 @@ func (r *Repository) RebaseSession(ref reference.Reference) (*RebaseSession, err
 // RebaseSessionByImage starts and returns a new RebaseSession with the given image as new base.
 // An error is returned if the image is not part of the repository.
 func (r *Repository) RebaseSessionByImage(baseImage *libimage.Image) (*RebaseSession, error) {
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return nil, err
+	}
+
 	names, err := baseImage.NamedRepoTags()
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve named references to new base image: %w", err)
@@ -255,10 +335,20 @@ func (r *Repository) RebaseSessionByImage(baseImage *libimage.Image) (*RebaseSes
 }
 
 func commit(builder *buildah.Builder, options CommitOptions, sref types.ImageReference, systemContext *types.SystemContext) error {
-	builder.SetHistoryComment(options.Message + "\n")
+	record := options.Record
+	if record == nil {
+		record = operationRecordFromCreatedBy(options.CreatedBy)
+	}
+
+	comment, err := historyComment(options.Message, record)
+	if err != nil {
+		return err
+	}
+
+	builder.SetHistoryComment(comment)
 	builder.SetCreatedBy(CommitPrefix + options.CreatedBy)
 
-	_, _, _, err := builder.Commit(context.Background(), sref, buildah.CommitOptions{
+	_, _, _, err = builder.Commit(context.Background(), sref, buildah.CommitOptions{
 		PreferredManifestType: "",
 		Compression:           archive.Gzip,
 		SignaturePolicyPath:   "",
@@ -267,7 +357,7 @@ func commit(builder *buildah.Builder, options CommitOptions, sref types.ImageRef
 		HistoryTimestamp:      nil,
 		SystemContext:         systemContext,
 		IIDFile:               "",
-		Squash:                false,
+		Squash:                options.Squash,
 		OmitHistory:           false,
 		BlobDirectory:         "",
 		EmptyLayer:            false,
@@ -56,7 +56,9 @@ func TestRepositoryAdd(t *testing.T) {
 
 		// Check commit message
 		// We're splitting history comment as buildah append text to comment
-		require.Equal(t, commitMsg, strings.Split(history[0].Comment, "\nFROM")[0], "wrong commit message")
+		message, rawRecord := splitHistoryComment(strings.Split(history[0].Comment, "\nFROM")[0])
+		require.Equal(t, commitMsg, message, "wrong commit message")
+		require.NotEmpty(t, rawRecord, "operation record is missing")
 
 		// Check created by
 		wd, err := os.Getwd()
@@ -65,6 +67,18 @@ func TestRepositoryAdd(t *testing.T) {
 			wd, "https://example.com/index.html")
 		require.Equal(t, expectedCreatedBy, history[0].CreatedBy, "wrong CreatedBy field")
 
+		// Check operation record
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		addOp, err := commits[0].AddOperation()
+		require.NoError(t, err)
+		require.Equal(t, AddOperation{
+			Sources: []string{wd, "https://example.com/index.html"},
+			Dest:    "/",
+			Chown:   "",
+			Chmod:   "",
+		}, addOp)
+
 		// Check HEAD of repository is up to date
 		require.Equal(t, repo.ID(), history[0].ID, "repository id and commit id differ")
 	})
@@ -151,13 +165,29 @@ func TestRepositoryExec(t *testing.T) {
 
 	// Check commit message
 	// We're splitting history comment as buildah append text to comment
-	require.Equal(t, commitMsg, strings.Split(history[0].Comment, "\nFROM")[0], "wrong commit message")
+	message, rawRecord := splitHistoryComment(strings.Split(history[0].Comment, "\nFROM")[0])
+	require.Equal(t, commitMsg, message, "wrong commit message")
+	require.NotEmpty(t, rawRecord, "operation record is missing")
 
 	// Check created by
 	expectedCreatedBy := fmt.Sprintf(`/bin/sh -c #(ocitree) EXEC [%q %q %q]`,
 		"/bin/sh", "-c", cmd)
 	require.Equal(t, expectedCreatedBy, history[0].CreatedBy, "wrong CreatedBy field")
 
+	// Check operation record
+	commits, err := repo.Commits()
+	require.NoError(t, err)
+	execOp, err := commits[0].ExecOperation()
+	require.NoError(t, err)
+	require.Equal(t, ExecOperation{
+		Argv: []string{"/bin/sh", "-c", cmd},
+		Env:  nil,
+		User: "root",
+	}, execOp)
+
+	_, err = commits[0].AddOperation()
+	require.ErrorIs(t, err, ErrOperationMismatch)
+
 	// Check HEAD of repository is up to date
 	require.Equal(t, repo.ID(), history[0].ID, "repository id and commit id differ")
 }
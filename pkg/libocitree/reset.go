@@ -0,0 +1,223 @@
+package libocitree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containers/buildah"
+	"github.com/containers/storage/pkg/chrootarchive"
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+var (
+	ErrUnknownResetMode = errors.New("unknown reset mode")
+)
+
+// ResetMode define how commits discarded by a reset and uncommitted changes
+// are handled.
+type ResetMode uint
+
+const (
+	// MixedResetMode discards commits but keeps uncommitted changes.
+	MixedResetMode ResetMode = iota
+	// SoftResetMode collapses discarded commits changes and uncommitted
+	// changes into the working container.
+	SoftResetMode
+	// HardResetMode discards commits and uncommitted changes.
+	HardResetMode
+	UnknownResetMode
+)
+
+// String implements fmt.Stringer.
+func (rm ResetMode) String() string {
+	switch rm {
+	case MixedResetMode:
+		return "mixed"
+	case SoftResetMode:
+		return "soft"
+	case HardResetMode:
+		return "hard"
+	default:
+		return "unknown"
+	}
+}
+
+// ResetModeFromString parses the given reset mode.
+func ResetModeFromString(str string) ResetMode {
+	switch strings.ToLower(str) {
+	case "mixed", "":
+		return MixedResetMode
+	case "soft":
+		return SoftResetMode
+	case "hard":
+		return HardResetMode
+	default:
+		return UnknownResetMode
+	}
+}
+
+// Reset moves HEAD to the given reference. Discarded commits, ordered from
+// newer to older, are returned. Using SoftResetMode, changes of discarded
+// commits are kept in the working container and can be committed as a
+// single commit using CommitWorkingContainer.
+func (r *Repository) Reset(ref reference.Reference, mode ResetMode) (Commits, error) {
+	if mode == UnknownResetMode {
+		return nil, ErrUnknownResetMode
+	}
+	if r.RebaseInProgress() {
+		return nil, ErrRebaseInProgress
+	}
+
+	target, err := r.Commit(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	discarded, err := r.discardedCommits(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// Layers to apply on working container once HEAD is moved
+	layers := make([][]byte, 0, 2)
+
+	if mode == SoftResetMode && target.ID() != r.ID() {
+		layer, err := readDiff(r.runtime.diff(target.ID(), r.ID()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute changes of discarded commits: %w", err)
+		}
+		layers = append(layers, layer)
+	}
+
+	builder, err := r.runtime.workingContainer(r.Name())
+	if err != nil {
+		return nil, err
+	}
+	if builder != nil && mode != HardResetMode {
+		clean, err := r.isWorkingContainerClean(builder)
+		if err != nil {
+			return nil, err
+		}
+
+		if !clean {
+			layer, err := readDiff(r.runtime.containerDiff(builder.ContainerID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute uncommitted changes: %w", err)
+			}
+			layers = append(layers, layer)
+		}
+	}
+
+	// Build the new working container before moving HEAD so uncommitted
+	// changes aren't lost if applying them fails.
+	var newBuilder *buildah.Builder
+	if len(layers) > 0 {
+		newBuilder, err = r.runtime.repoBuilder(ref, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create working container: %w", err)
+		}
+
+		err = applyLayers(newBuilder, layers...)
+		if err != nil {
+			_ = newBuilder.Delete()
+			return nil, err
+		}
+	}
+
+	// Move HEAD
+	err = r.checkout(ref, ResetReflogOperation, ref.String())
+	if err != nil {
+		if newBuilder != nil {
+			_ = newBuilder.Delete()
+		}
+		return nil, err
+	}
+
+	// Replace working container
+	if builder != nil {
+		err = builder.Delete()
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete working container: %w", err)
+		}
+	}
+
+	if newBuilder != nil {
+		err = r.runtime.setWorkingContainer(r.Name(), newBuilder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return discarded, nil
+}
+
+// discardedCommits returns commits of HEAD that aren't part of the given
+// reference history.
+func (r *Repository) discardedCommits(ref reference.Reference) (Commits, error) {
+	img, err := r.runtime.lookupImage(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup reset reference: %w", err)
+	}
+
+	commits, err := r.Commits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve repository commits: %w", err)
+	}
+
+	for i := range commits {
+		if commits[i].ID() == img.ID() {
+			return commits[:i], nil
+		}
+	}
+
+	// Reference isn't an ancestor of HEAD, discard commits until a common
+	// ancestor.
+	targetRepo := &Repository{
+		headRef: r.headRef,
+		runtime: r.runtime,
+		head:    img,
+	}
+	targetCommits, err := targetRepo.Commits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve reset reference commits: %w", err)
+	}
+
+	for i := range commits {
+		if hasImage(&commits[i]) && targetCommits.indexOf(commits[i].ID()) != -1 {
+			return commits[:i], nil
+		}
+	}
+
+	return commits, nil
+}
+
+// applyLayers applies the given layers on builder container.
+func applyLayers(builder *buildah.Builder, layers ...[]byte) error {
+	mountpoint, err := builder.Mount("")
+	if err != nil {
+		return fmt.Errorf("failed to mount container: %w", err)
+	}
+	defer builder.Unmount()
+
+	for _, layer := range layers {
+		_, err = chrootarchive.ApplyLayer(mountpoint, bytes.NewReader(layer))
+		if err != nil {
+			return fmt.Errorf("failed to apply changes on container: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readDiff reads and closes the given diff.
+func readDiff(diff io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Close()
+
+	return io.ReadAll(diff)
+}
@@ -0,0 +1,152 @@
+package libocitree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+// setupResetTest clones alpine, adds 3 commits and returns a reference to the
+// commit before them.
+func setupResetTest(t *testing.T) (*Manager, func(), *Repository, reference.Reference) {
+	manager, cleanup := newTestManager(t)
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		err = repo.Exec(ExecOptions{
+			Stdin:        nil,
+			Stdout:       nil,
+			Stderr:       nil,
+			Message:      fmt.Sprintf("commit %d", i),
+			ReportWriter: nil,
+		}, "/bin/sh", "-c", fmt.Sprintf("touch /commit%d", i))
+		require.NoError(t, err)
+	}
+
+	resetRef, err := manager.ResolveRelativeReference(reference.RelativeFromReferenceAndOffset(repo.HeadRef(), 3))
+	require.NoError(t, err)
+
+	return manager, cleanup, repo, resetRef
+}
+
+func writeWorkingContainerFile(t *testing.T, repo *Repository, name string) {
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+	defer repo.Unmount()
+
+	err = os.WriteFile(filepath.Join(mountpoint, name), []byte(name), 0644)
+	require.NoError(t, err)
+}
+
+func requireWorkingContainerFiles(t *testing.T, repo *Repository, exist []string, notExist []string) {
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+	defer repo.Unmount()
+
+	for _, name := range exist {
+		require.FileExists(t, filepath.Join(mountpoint, name))
+	}
+	for _, name := range notExist {
+		require.NoFileExists(t, filepath.Join(mountpoint, name))
+	}
+}
+
+func requireDiscardedCommits(t *testing.T, discarded Commits) {
+	require.Len(t, discarded, 3)
+	for i, commit := range discarded {
+		require.Equal(t, fmt.Sprintf("commit %d", 3-i), commit.Message())
+	}
+}
+
+func TestRepositoryReset(t *testing.T) {
+	t.Run("Hard", func(t *testing.T) {
+		_, cleanup, repo, resetRef := setupResetTest(t)
+		defer cleanup()
+
+		writeWorkingContainerFile(t, repo, "uncommitted")
+
+		discarded, err := repo.Reset(resetRef, HardResetMode)
+		require.NoError(t, err)
+		requireDiscardedCommits(t, discarded)
+		require.Equal(t, resetRef.IdOrTag()[len(reference.IdPrefix):], repo.ID())
+
+		requireWorkingContainerFiles(t, repo, nil, []string{"commit1", "commit2", "commit3", "uncommitted"})
+	})
+
+	t.Run("Mixed", func(t *testing.T) {
+		_, cleanup, repo, resetRef := setupResetTest(t)
+		defer cleanup()
+
+		writeWorkingContainerFile(t, repo, "uncommitted")
+
+		discarded, err := repo.Reset(resetRef, MixedResetMode)
+		require.NoError(t, err)
+		requireDiscardedCommits(t, discarded)
+		require.Equal(t, resetRef.IdOrTag()[len(reference.IdPrefix):], repo.ID())
+
+		requireWorkingContainerFiles(t, repo, []string{"uncommitted"}, []string{"commit1", "commit2", "commit3"})
+	})
+
+	t.Run("Soft", func(t *testing.T) {
+		_, cleanup, repo, resetRef := setupResetTest(t)
+		defer cleanup()
+
+		writeWorkingContainerFile(t, repo, "uncommitted")
+
+		discarded, err := repo.Reset(resetRef, SoftResetMode)
+		require.NoError(t, err)
+		requireDiscardedCommits(t, discarded)
+		require.Equal(t, resetRef.IdOrTag()[len(reference.IdPrefix):], repo.ID())
+
+		requireWorkingContainerFiles(t, repo, []string{"commit1", "commit2", "commit3", "uncommitted"}, nil)
+
+		// Discarded changes are committed as a single commit
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+
+		err = repo.CommitWorkingContainer(WorkingContainerCommitOptions{
+			Message:      "commit 1, 2 & 3",
+			ReportWriter: nil,
+		})
+		require.NoError(t, err)
+
+		newCommits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Len(t, newCommits, len(commits)+1)
+	})
+
+	t.Run("RebaseInProgress", func(t *testing.T) {
+		_, cleanup, repo, _ := setupInterruptedRebaseTest(t)
+		defer cleanup()
+
+		_, err := repo.Reset(repo.HeadRef(), HardResetMode)
+		require.ErrorIs(t, err, ErrRebaseInProgress)
+	})
+}
+
+func TestResetModeFromString(t *testing.T) {
+	require.Equal(t, MixedResetMode, ResetModeFromString(""))
+	require.Equal(t, SoftResetMode, ResetModeFromString("soft"))
+	require.Equal(t, MixedResetMode, ResetModeFromString("mixed"))
+	require.Equal(t, HardResetMode, ResetModeFromString("HARD"))
+	require.Equal(t, UnknownResetMode, ResetModeFromString("keep"))
+}
@@ -0,0 +1,117 @@
+package libocitree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/containers/storage/pkg/chrootarchive"
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+var (
+	ErrRevertCommitNotInHistory = errors.New("reverted commit is not part of HEAD history")
+	ErrRevertCommitHasNoParent  = errors.New("can't revert a commit without parent")
+)
+
+// RevertOptions holds options for Repository.Revert method.
+type RevertOptions struct {
+	// Message is the message of the revert commit. It defaults to
+	// Revert "<reverted commit subject>".
+	Message string
+	// ConflictStrategy define how conflicts with commits following the
+	// reverted commit are resolved.
+	ConflictStrategy RebaseConflictStrategy
+
+	ReportWriter io.Writer
+}
+
+// Revert commits the inverse of the commit with the given reference on HEAD:
+// files modified or deleted by the commit are restored and files added are
+// deleted. Commit must be part of HEAD history. A *RebaseConflictError is
+// returned if a path changed by the reverted commit was changed again later.
+func (r *Repository) Revert(ref reference.Reference, options RevertOptions) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+	err := r.ensureCleanWorkingContainer()
+	if err != nil {
+		return err
+	}
+
+	commit, err := r.Commit(ref)
+	if err != nil {
+		return err
+	}
+
+	commits, err := r.Commits()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve repository commits: %w", err)
+	}
+	if !hasImage(commit) || commits.indexOf(commit.ID()) == -1 {
+		return ErrRevertCommitNotInHistory
+	}
+
+	parent := commit.Parent()
+	if parent == nil {
+		return ErrRevertCommitHasNoParent
+	}
+	if !hasImage(parent) {
+		return fmt.Errorf("failed to retrieve parent of commit %v: %w", commit.ID(), ErrCommitHasNoImageAssociated)
+	}
+
+	// Diff from commit to its parent is the inverse of the commit.
+	inverse, err := readDiff(r.runtime.diff(commit.ID(), parent.ID()))
+	if err != nil {
+		return fmt.Errorf("failed to compute inverse of commit %v: %w", commit.ID(), err)
+	}
+
+	// Paths changed by later commits
+	laterChanges := make(map[string]archive.ChangeType)
+	if commit.ID() != r.ID() {
+		changes, err := r.runtime.changes(commit.ID(), r.ID())
+		if err != nil {
+			return fmt.Errorf("failed to compute changes made after commit %v: %w", commit.ID(), err)
+		}
+		for _, change := range changes {
+			laterChanges[change.Path] = change.Kind
+		}
+	}
+
+	builder, err := r.runtime.repoBuilder(r.headRef, options.ReportWriter)
+	if err != nil {
+		return err
+	}
+	defer builder.Delete()
+
+	mountpoint, err := builder.Mount("")
+	if err != nil {
+		return fmt.Errorf("failed to mount builder container: %w", err)
+	}
+	defer builder.Unmount()
+
+	inverse, err = resolveConflicts(mountpoint, commit.ID(), inverse, laterChanges, options.ConflictStrategy)
+	if err != nil {
+		return err
+	}
+
+	_, err = chrootarchive.ApplyLayer(mountpoint, bytes.NewReader(inverse))
+	if err != nil {
+		return fmt.Errorf("failed to apply inverse of commit %v: %w", commit.ID(), err)
+	}
+
+	message := options.Message
+	if message == "" {
+		message = fmt.Sprintf("Revert %q", messageSubject(commit.Message()))
+	}
+
+	return r.commit(builder, RevertReflogOperation, CommitOptions{
+		CreatedBy:    RevertCommitOperation.String() + " " + commit.ID(),
+		Message:      message,
+		Squash:       false,
+		Record:       nil,
+		ReportWriter: options.ReportWriter,
+	})
+}
@@ -0,0 +1,123 @@
+package libocitree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryRevert(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	exec := func(msg, cmd string) {
+		err := repo.Exec(ExecOptions{
+			Stdin:        nil,
+			Stdout:       nil,
+			Stderr:       nil,
+			Message:      msg,
+			ReportWriter: nil,
+		}, "/bin/sh", "-c", cmd)
+		require.NoError(t, err)
+	}
+
+	requireFiles := func(exist []string, notExist []string) {
+		mountpoint, err := repo.Mount()
+		require.NoError(t, err)
+		defer repo.Unmount()
+
+		for _, name := range exist {
+			require.FileExists(t, filepath.Join(mountpoint, name))
+		}
+		for _, name := range notExist {
+			require.NoFileExists(t, filepath.Join(mountpoint, name))
+		}
+	}
+
+	exec("commit 1", "echo v1 > /file && touch /added1 && rm /etc/motd")
+	commit1ID := repo.ID()
+	commit1Ref, err := manager.ResolveRelativeReference(reference.RelativeFromReferenceAndOffset(repo.HeadRef(), 0))
+	require.NoError(t, err)
+	exec("commit 2", "touch /commit2")
+
+	t.Run("Valid", func(t *testing.T) {
+		err := repo.Revert(commit1Ref, RevertOptions{
+			Message:          "",
+			ConflictStrategy: FailRebaseConflictStrategy,
+			ReportWriter:     nil,
+		})
+		require.NoError(t, err)
+
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Equal(t, RevertCommitOperation, commits[0].Operation())
+		require.Equal(t, commit1ID, commits[0].RevertedID())
+		require.Equal(t, `Revert "commit 1"`, commits[0].Message())
+
+		requireFiles([]string{"etc/motd", "commit2"}, []string{"file", "added1"})
+	})
+
+	t.Run("ParentWithoutImage", func(t *testing.T) {
+		// Parent of alpine image top commit has no image associated.
+		latestRef := reference.NewLocal(ref.Name(), reference.LocalTagFromTag(reference.LatestTag))
+		err := repo.Revert(latestRef, RevertOptions{})
+		require.ErrorIs(t, err, ErrCommitHasNoImageAssociated)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		// Reapply commit 1 and modify /file again
+		exec("commit 1 bis", "echo v1 > /file")
+		commit1BisRef, err := manager.ResolveRelativeReference(reference.RelativeFromReferenceAndOffset(repo.HeadRef(), 0))
+		require.NoError(t, err)
+		exec("commit 3", "echo v2 > /file")
+
+		headID := repo.ID()
+		err = repo.Revert(commit1BisRef, RevertOptions{})
+		require.Error(t, err)
+
+		conflictErr := &RebaseConflictError{}
+		require.True(t, errors.As(err, &conflictErr), "error isn't a conflict error")
+		require.Len(t, conflictErr.Conflicts, 1)
+		require.Equal(t, "/file", conflictErr.Conflicts[0].Path)
+		require.True(t, conflictErr.Conflicts[0].Commit.Deleted)
+		require.Equal(t, headID, repo.ID(), "HEAD moved on conflict")
+
+		// Keep HEAD version
+		err = repo.Revert(commit1BisRef, RevertOptions{
+			Message:          "",
+			ConflictStrategy: OursRebaseConflictStrategy,
+			ReportWriter:     nil,
+		})
+		require.NoError(t, err)
+		requireFiles([]string{"file"}, nil)
+
+		// Use reverted commit version
+		err = repo.Revert(commit1BisRef, RevertOptions{
+			Message:          "",
+			ConflictStrategy: TheirsRebaseConflictStrategy,
+			ReportWriter:     nil,
+		})
+		require.NoError(t, err)
+		requireFiles(nil, []string{"file"})
+	})
+}
@@ -0,0 +1,125 @@
+package libocitree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/negrel/ocitree/pkg/reference"
+)
+
+// Status define the state of a repository and of its working container.
+type Status struct {
+	// HeadID is the ID of HEAD.
+	HeadID string
+	// HeadTags contains other tags pointing to HEAD.
+	HeadTags []reference.Tag
+	// Mountpoint is the mountpoint of the working container or an empty
+	// string if it isn't mounted.
+	Mountpoint string
+	// RebaseInProgress is true if a rebase was started but neither finished
+	// nor aborted.
+	RebaseInProgress bool
+	// WorkingContainerOutdated is true if the working container is based
+	// on a previous HEAD.
+	WorkingContainerOutdated bool
+	// Changes contains uncommitted changes of the working container.
+	Changes []FileChange
+}
+
+// IsMounted returns true if working container is mounted.
+func (s Status) IsMounted() bool {
+	return s.Mountpoint != ""
+}
+
+// IsClean returns true if working container has no changes.
+func (s Status) IsClean() bool {
+	return len(s.Changes) == 0
+}
+
+// Status returns the status of the repository.
+func (r *Repository) Status() (Status, error) {
+	status := Status{
+		HeadID:                   r.ID(),
+		HeadTags:                 r.OtherHeadTags(),
+		Mountpoint:               "",
+		RebaseInProgress:         r.RebaseInProgress(),
+		WorkingContainerOutdated: false,
+		Changes:                  []FileChange{},
+	}
+
+	builder, err := r.runtime.workingContainer(r.Name())
+	if err != nil {
+		return Status{}, err
+	}
+	if builder == nil {
+		return status, nil
+	}
+
+	status.Mountpoint = builder.MountPoint
+	status.WorkingContainerOutdated = builder.FromImageID != r.ID()
+
+	changes, err := r.runtime.containerChanges(builder.ContainerID)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to retrieve working container changes: %w", err)
+	}
+	status.Changes = fileChangesFromArchive(changes)
+
+	return status, nil
+}
+
+// fileChangesFromArchive converts the given changes into FileChange. Modified
+// directories containing other changes are omitted. FileVersion of changes
+// are unknown except for deleted and added files.
+func fileChangesFromArchive(changes []archive.Change) []FileChange {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	result := make([]FileChange, 0, len(changes))
+	for i, change := range changes {
+		fileChange := FileChange{
+			Path:       change.Path,
+			Kind:       ModifiedFileChange,
+			Old:        FileVersion{},
+			New:        FileVersion{},
+			OldContent: nil,
+			NewContent: nil,
+		}
+
+		switch change.Kind {
+		case archive.ChangeAdd:
+			fileChange.Kind = AddedFileChange
+			fileChange.Old.Deleted = true
+		case archive.ChangeDelete:
+			fileChange.Kind = DeletedFileChange
+			fileChange.New.Deleted = true
+		case archive.ChangeModify:
+			// Parent directories of changed files are reported as modified.
+			if hasChangeUnder(changes[i+1:], change.Path) {
+				continue
+			}
+		}
+
+		result = append(result, fileChange)
+	}
+
+	return result
+}
+
+// hasChangeUnder returns true if one of the given sorted changes is inside
+// dir.
+func hasChangeUnder(changes []archive.Change, dir string) bool {
+	for _, change := range changes {
+		// Paths inside dir are contiguous as changes are sorted.
+		if !strings.HasPrefix(change.Path, dir) {
+			return false
+		}
+		if isPathUnder(change.Path, dir) {
+			return true
+		}
+	}
+
+	return false
+}
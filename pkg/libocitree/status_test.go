@@ -0,0 +1,95 @@
+package libocitree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileChangesFromArchive(t *testing.T) {
+	changes := fileChangesFromArchive([]archive.Change{
+		{Path: "/etc/motd", Kind: archive.ChangeModify},
+		{Path: "/etc", Kind: archive.ChangeModify},
+		{Path: "/etc-dir", Kind: archive.ChangeModify},
+		{Path: "/added", Kind: archive.ChangeAdd},
+		{Path: "/root", Kind: archive.ChangeDelete},
+	})
+
+	actual := make([]string, len(changes))
+	for i, change := range changes {
+		actual[i] = change.Kind.Short() + " " + change.Path
+	}
+	require.Equal(t, []string{
+		"A /added",
+		"M /etc-dir",
+		"M /etc/motd",
+		"D /root",
+	}, actual)
+}
+
+func TestRepositoryStatus(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	status, err := repo.Status()
+	require.NoError(t, err)
+	require.Equal(t, repo.ID(), status.HeadID)
+	requireEqualTags(t, []string{reference.Latest}, status.HeadTags)
+	require.False(t, status.IsMounted())
+	require.False(t, status.RebaseInProgress)
+	require.False(t, status.WorkingContainerOutdated)
+	require.True(t, status.IsClean())
+
+	mountpoint, err := repo.Mount()
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(mountpoint, "added"), []byte("added"), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(mountpoint, "etc", "motd"), []byte("modified"), 0644)
+	require.NoError(t, err)
+	err = os.Remove(filepath.Join(mountpoint, "etc", "issue"))
+	require.NoError(t, err)
+
+	status, err = repo.Status()
+	require.NoError(t, err)
+	require.True(t, status.IsMounted())
+	require.Equal(t, mountpoint, status.Mountpoint)
+
+	kinds := make(map[string]FileChangeKind)
+	for _, change := range status.Changes {
+		kinds[change.Path] = change.Kind
+	}
+	require.Equal(t, map[string]FileChangeKind{
+		"/added":     AddedFileChange,
+		"/etc/motd":  ModifiedFileChange,
+		"/etc/issue": DeletedFileChange,
+	}, kinds)
+
+	err = repo.Unmount()
+	require.NoError(t, err)
+
+	status, err = repo.Status()
+	require.NoError(t, err)
+	require.False(t, status.IsMounted())
+	require.Len(t, status.Changes, 3)
+}
@@ -0,0 +1,177 @@
+package libocitree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/containers/buildah"
+)
+
+var (
+	ErrNothingToCommit          = errors.New("nothing to commit, working container is clean")
+	ErrWorkingContainerOutdated = errors.New("working container isn't based on HEAD")
+	ErrWorkingContainerDirty    = errors.New("working container has uncommitted changes, commit or reset them first")
+)
+
+// headWorkingContainer returns the working container of this repository based on
+// HEAD. A new one is created if there is none. Outdated working containers
+// without changes are replaced.
+func (r *Repository) headWorkingContainer() (*buildah.Builder, error) {
+	builder, err := r.runtime.workingContainer(r.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if builder != nil && builder.FromImageID != r.ID() {
+		clean, err := r.isWorkingContainerClean(builder)
+		if err != nil {
+			return nil, err
+		}
+		if !clean {
+			return nil, ErrWorkingContainerOutdated
+		}
+
+		err = builder.Delete()
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete outdated working container: %w", err)
+		}
+		builder = nil
+	}
+
+	if builder == nil {
+		builder, err = r.runtime.newWorkingContainer(r.headRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create working container: %w", err)
+		}
+	}
+
+	return builder, nil
+}
+
+// ensureCleanWorkingContainer returns ErrWorkingContainerDirty if working
+// container of the repository has uncommitted changes. Operations moving HEAD
+// must call it so changes aren't left on an outdated working container.
+func (r *Repository) ensureCleanWorkingContainer() error {
+	builder, err := r.runtime.workingContainer(r.Name())
+	if err != nil {
+		return err
+	}
+	if builder == nil {
+		return nil
+	}
+
+	clean, err := r.isWorkingContainerClean(builder)
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return ErrWorkingContainerDirty
+	}
+
+	return nil
+}
+
+func (r *Repository) isWorkingContainerClean(builder *buildah.Builder) (bool, error) {
+	changes, err := r.runtime.containerChanges(builder.ContainerID)
+	if err != nil {
+		return false, err
+	}
+
+	return len(changes) == 0, nil
+}
+
+// Mount mounts the working container of the repository and returns the
+// mountpoint. Working container is created from HEAD if needed, changes made
+// in it can be committed using CommitWorkingContainer.
+// Mountpoint isn't HEAD image rootfs anymore: changes made in it are
+// persisted in the working container until they're committed or discarded
+// using a hard reset. Operations moving HEAD fail with
+// ErrWorkingContainerDirty while working container has uncommitted changes.
+func (r *Repository) Mount() (string, error) {
+	builder, err := r.headWorkingContainer()
+	if err != nil {
+		return "", err
+	}
+
+	mountpoint, err := builder.Mount("")
+	if err != nil {
+		return "", fmt.Errorf("failed to mount working container: %w", err)
+	}
+
+	return mountpoint, nil
+}
+
+// Unmount unmount the working container of the repository. Unlike HEAD image
+// mounts, working container and its uncommitted changes are kept.
+func (r *Repository) Unmount() error {
+	builder, err := r.runtime.workingContainer(r.Name())
+	if err != nil {
+		return err
+	}
+	if builder == nil {
+		return nil
+	}
+
+	err = builder.Unmount()
+	if err != nil {
+		return fmt.Errorf("failed to unmount working container: %w", err)
+	}
+
+	return nil
+}
+
+// WorkingContainerCommitOptions holds options for
+// Repository.CommitWorkingContainer method.
+type WorkingContainerCommitOptions struct {
+	Message      string
+	ReportWriter io.Writer
+}
+
+// CommitWorkingContainer commits changes made in the working container of the
+// repository and removes it. ErrNothingToCommit is returned if there is no
+// changes.
+func (r *Repository) CommitWorkingContainer(options WorkingContainerCommitOptions) error {
+	if r.RebaseInProgress() {
+		return ErrRebaseInProgress
+	}
+
+	builder, err := r.runtime.workingContainer(r.Name())
+	if err != nil {
+		return err
+	}
+	if builder == nil {
+		return ErrNothingToCommit
+	}
+
+	if builder.FromImageID != r.ID() {
+		return ErrWorkingContainerOutdated
+	}
+
+	clean, err := r.isWorkingContainerClean(builder)
+	if err != nil {
+		return err
+	}
+	if clean {
+		return ErrNothingToCommit
+	}
+
+	err = r.commit(builder, CommitReflogOperation, CommitOptions{
+		CreatedBy:    CommitCommitOperation.String(),
+		Message:      options.Message,
+		Squash:       false,
+		Record:       nil,
+		ReportWriter: options.ReportWriter,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reset working container
+	err = builder.Delete()
+	if err != nil {
+		return fmt.Errorf("failed to delete working container: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package libocitree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/negrel/ocitree/pkg/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryCommitWorkingContainer(t *testing.T) {
+	manager, cleanup := newTestManager(t)
+	defer cleanup()
+
+	ref, err := reference.RemoteRefFromString("alpine")
+	require.NoError(t, err)
+
+	// Clone alpine image
+	err = manager.Clone(ref, CloneOptions{
+		PullOptions: PullOptions{
+			MaxRetries:   0,
+			RetryDelay:   0,
+			ReportWriter: os.Stderr,
+		},
+	})
+	require.NoError(t, err)
+
+	repo, err := manager.Repository(ref.Name())
+	require.NoError(t, err)
+
+	t.Run("NothingToCommit", func(t *testing.T) {
+		err := repo.CommitWorkingContainer(WorkingContainerCommitOptions{
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrNothingToCommit)
+
+		_, err = repo.Mount()
+		require.NoError(t, err)
+		defer repo.Unmount()
+
+		err = repo.CommitWorkingContainer(WorkingContainerCommitOptions{
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrNothingToCommit)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		commits, err := repo.Commits()
+		require.NoError(t, err)
+
+		mountpoint, err := repo.Mount()
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(mountpoint, "commit1"), []byte("commit 1"), 0644)
+		require.NoError(t, err)
+		err = os.Remove(filepath.Join(mountpoint, "etc", "motd"))
+		require.NoError(t, err)
+
+		commitMsg := randomCommitMessage()
+		err = repo.CommitWorkingContainer(WorkingContainerCommitOptions{
+			Message:      commitMsg,
+			ReportWriter: os.Stderr,
+		})
+		require.NoError(t, err)
+
+		newCommits, err := repo.Commits()
+		require.NoError(t, err)
+		require.Len(t, newCommits, len(commits)+1)
+		require.Equal(t, repo.ID(), newCommits[0].ID())
+		require.Equal(t, CommitCommitOperation, newCommits[0].Operation())
+		require.Equal(t, commitMsg, newCommits[0].Message())
+
+		// Working container was reset on new HEAD
+		mountpoint, err = repo.Mount()
+		require.NoError(t, err)
+		defer repo.Unmount()
+
+		require.FileExists(t, filepath.Join(mountpoint, "commit1"))
+		require.NoFileExists(t, filepath.Join(mountpoint, "etc", "motd"))
+
+		err = repo.CommitWorkingContainer(WorkingContainerCommitOptions{
+			Message:      "",
+			ReportWriter: nil,
+		})
+		require.ErrorIs(t, err, ErrNothingToCommit)
+	})
+
+	t.Run("Dirty", func(t *testing.T) {
+		headID := repo.ID()
+
+		mountpoint, err := repo.Mount()
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(mountpoint, "dirty"), []byte("dirty"), 0644)
+		require.NoError(t, err)
+
+		err = repo.Unmount()
+		require.NoError(t, err)
+
+		// Operations moving HEAD are refused
+		err = repo.Exec(ExecOptions{
+			Stdin:        nil,
+			Stdout:       nil,
+			Stderr:       nil,
+			Message:      "",
+			ReportWriter: nil,
+		}, "/bin/sh", "-c", "touch /exec")
+		require.ErrorIs(t, err, ErrWorkingContainerDirty)
+
+		err = repo.Checkout(reference.NewLocal(repo.Name(), reference.LocalTagFromTag(reference.LatestTag)))
+		require.ErrorIs(t, err, ErrWorkingContainerDirty)
+		require.Equal(t, headID, repo.ID())
+
+		// Changes are still there
+		mountpoint, err = repo.Mount()
+		require.NoError(t, err)
+		require.FileExists(t, filepath.Join(mountpoint, "dirty"))
+
+		err = repo.Unmount()
+		require.NoError(t, err)
+
+		// Discard changes
+		_, err = repo.Reset(repo.HeadRef(), HardResetMode)
+		require.NoError(t, err)
+
+		err = repo.Exec(ExecOptions{
+			Stdin:        nil,
+			Stdout:       nil,
+			Stderr:       nil,
+			Message:      "",
+			ReportWriter: nil,
+		}, "/bin/sh", "-c", "touch /exec")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,128 @@
+package reference
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeNow is used to resolve relative dates, it is replaced in tests.
+var timeNow = time.Now
+
+var (
+	ErrInvalidDateFormat = errors.New("invalid date format")
+)
+
+var relativeDateRegex = regexp.MustCompile(`^(\d+)[. ](second|minute|hour|day|week|month|year)s?[. ]ago$`)
+
+var absoluteDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ReflogSelector selects a previous position of HEAD in a repository reflog.
+// Positions are selected either by index, 0 being the current position, or by
+// date.
+type ReflogSelector struct {
+	raw   string
+	index uint
+	date  *time.Time
+}
+
+// ReflogSelectorFromString parses the given reflog selector. Selector is
+// either an index (e.g. "3"), "now", "yesterday", a relative date
+// (e.g. "2.hours.ago", "3 days ago") or an absolute date (e.g. "2022-11-02").
+func ReflogSelectorFromString(selector string) (ReflogSelector, error) {
+	selector = strings.TrimSpace(selector)
+
+	if index, err := strconv.ParseUint(selector, 10, 0); err == nil {
+		return ReflogSelector{raw: selector, index: uint(index), date: nil}, nil
+	}
+
+	date, err := parseReflogDate(selector)
+	if err != nil {
+		return ReflogSelector{}, err
+	}
+
+	return ReflogSelector{raw: selector, index: 0, date: &date}, nil
+}
+
+// DateFromString parses the given date using formats accepted by reflog
+// selectors: "now", "yesterday", a relative date or an absolute date.
+func DateFromString(date string) (time.Time, error) {
+	parsed, err := parseReflogDate(strings.TrimSpace(date))
+	if err != nil {
+		return time.Time{}, ErrInvalidDateFormat
+	}
+
+	return parsed, nil
+}
+
+func parseReflogDate(selector string) (time.Time, error) {
+	now := timeNow()
+	lower := strings.ToLower(selector)
+
+	switch lower {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	if match := relativeDateRegex.FindStringSubmatch(lower); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, ErrInvalidReflogSelectorFormat
+		}
+
+		switch match[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		case "year":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	for _, layout := range absoluteDateLayouts {
+		date, err := time.ParseInLocation(layout, selector, time.Local)
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidReflogSelectorFormat
+}
+
+// Index returns the index of the selected reflog entry, second returned value
+// is false if the selector selects an entry by date.
+func (rs ReflogSelector) Index() (uint, bool) {
+	return rs.index, rs.date == nil
+}
+
+// Date returns the date of the selected reflog entry, second returned value
+// is false if the selector selects an entry by index.
+func (rs ReflogSelector) Date() (time.Time, bool) {
+	if rs.date == nil {
+		return time.Time{}, false
+	}
+
+	return *rs.date, true
+}
+
+// String implements fmt.Stringer.
+func (rs ReflogSelector) String() string {
+	return rs.raw
+}
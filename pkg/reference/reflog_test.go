@@ -0,0 +1,97 @@
+package reference
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReflogSelectorFromString(t *testing.T) {
+	now := time.Date(2022, time.November, 10, 12, 0, 0, 0, time.Local)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	for _, test := range []struct {
+		name          string
+		selector      string
+		expectedIndex uint
+		expectedDate  time.Time
+		expectedError error
+	}{
+		{
+			name:          "Index",
+			selector:      "3",
+			expectedIndex: 3,
+		},
+		{
+			name:         "Now",
+			selector:     "now",
+			expectedDate: now,
+		},
+		{
+			name:         "Yesterday",
+			selector:     "yesterday",
+			expectedDate: now.AddDate(0, 0, -1),
+		},
+		{
+			name:         "RelativeDate/Dots",
+			selector:     "2.hours.ago",
+			expectedDate: now.Add(-2 * time.Hour),
+		},
+		{
+			name:         "RelativeDate/Spaces",
+			selector:     "1 week ago",
+			expectedDate: now.AddDate(0, 0, -7),
+		},
+		{
+			name:         "AbsoluteDate",
+			selector:     "2022-11-02",
+			expectedDate: time.Date(2022, time.November, 2, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:          "Invalid",
+			selector:      "someday",
+			expectedError: ErrInvalidReflogSelectorFormat,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := ReflogSelectorFromString(test.selector)
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.selector, selector.String())
+
+			date, isDate := selector.Date()
+			if test.expectedDate.IsZero() {
+				require.False(t, isDate)
+				index, isIndex := selector.Index()
+				require.True(t, isIndex)
+				require.Equal(t, test.expectedIndex, index)
+				return
+			}
+
+			require.True(t, isDate)
+			require.True(t, test.expectedDate.Equal(date), "expected %v, got %v", test.expectedDate, date)
+		})
+	}
+}
+
+func TestDateFromString(t *testing.T) {
+	now := time.Date(2022, time.November, 10, 12, 0, 0, 0, time.Local)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	date, err := DateFromString(" 3.days.ago ")
+	require.NoError(t, err)
+	require.Equal(t, now.AddDate(0, 0, -3), date)
+
+	date, err = DateFromString("2022-11-02")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2022, time.November, 2, 0, 0, 0, 0, time.Local), date)
+
+	_, err = DateFromString("3")
+	require.ErrorIs(t, err, ErrInvalidDateFormat)
+}
@@ -8,25 +8,32 @@ import (
 )
 
 var (
-	ErrInvalidOffsetFormat = errors.New("invalid offset format")
+	ErrInvalidOffsetFormat         = errors.New("invalid offset format")
+	ErrInvalidReflogSelectorFormat = errors.New("invalid reflog selector format")
+	ErrReflogSelectorRequiresHead  = errors.New("reflog selector can only be applied to HEAD")
 )
 
 // Relative defines a relative reference.
 // It is made of a base and an offset.
 // Base is the reference on which the offset must be applied to get
-// an absolute reference.
+// an absolute reference. If the relative reference has a reflog selector,
+// offset must be applied to the HEAD position selected in the reflog instead.
 type Relative struct {
 	ref    Reference
+	reflog *ReflogSelector
 	offset uint
 }
 
 // RelativeFromReferenceAndOffset returns a new Relative reference base on the given
 // reference and offset.
 func RelativeFromReferenceAndOffset(ref Reference, offset uint) Relative {
-	return Relative{ref: ref, offset: offset}
+	return Relative{ref: ref, reflog: nil, offset: offset}
 }
 
-var offsetRegex = regexp.MustCompile(`(~\d+|\^+)$`)
+var (
+	offsetRegex = regexp.MustCompile(`(~\d+|\^+)$`)
+	reflogRegex = regexp.MustCompile(`@\{([^{}]+)\}$`)
+)
 
 // RelativeFromString parses the given string and returns relative reference
 // after validating and normalizing it. An error is returned if the reference is invalid.
@@ -49,16 +56,33 @@ func RelativeFromString(ref string) (Relative, error) {
 		}
 	}
 
+	// Parse reflog selector if there is one
+	var reflog *ReflogSelector
+	if match := reflogRegex.FindStringSubmatchIndex(ref); match != nil {
+		selector, err := ReflogSelectorFromString(ref[match[2]:match[3]])
+		if err != nil {
+			return Relative{}, err
+		}
+		reflog = &selector
+
+		// Strip reflog selector from ref
+		ref = ref[:match[0]]
+	}
+
 	// Parse base reference
 	baseRef, err := LocalRefFromString(ref)
 	if err != nil {
 		return Relative{}, err
 	}
+	if reflog != nil && baseRef.IdOrTag() != HeadTag.String() {
+		return Relative{}, ErrReflogSelectorRequiresHead
+	}
 
-	return RelativeFromReferenceAndOffset(
-		baseRef,
-		offset,
-	), nil
+	return Relative{
+		ref:    baseRef,
+		reflog: reflog,
+		offset: offset,
+	}, nil
 }
 
 // Base returns the base of the relative reference
@@ -66,6 +90,12 @@ func (r Relative) Base() Reference {
 	return r.ref
 }
 
+// Reflog returns the reflog selector of the relative reference or nil if
+// there is none.
+func (r Relative) Reflog() *ReflogSelector {
+	return r.reflog
+}
+
 // Offset returns the offset part of the relative reference.
 func (r Relative) Offset() uint {
 	return r.offset
@@ -100,5 +130,9 @@ func parseOffset(offset string) (uint, error) {
 
 // String implements fmt.Stringer.
 func (r Relative) String() string {
+	if r.reflog != nil {
+		return fmt.Sprintf("%v@{%v}~%d", r.ref.String(), r.reflog, r.offset)
+	}
+
 	return fmt.Sprintf("%v~%d", r.ref.String(), r.offset)
 }
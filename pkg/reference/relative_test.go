@@ -70,3 +70,30 @@ func TestRelativeFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestRelativeFromStringReflog(t *testing.T) {
+	ref, err := RelativeFromString("archlinux@{3}~2")
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/library/archlinux:HEAD", ref.Base().String())
+	require.Equal(t, uint(2), ref.Offset())
+	require.NotNil(t, ref.Reflog())
+	index, isIndex := ref.Reflog().Index()
+	require.True(t, isIndex)
+	require.Equal(t, uint(3), index)
+
+	ref, err = RelativeFromString("archlinux:HEAD@{yesterday}")
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/library/archlinux:HEAD", ref.Base().String())
+	_, isDate := ref.Reflog().Date()
+	require.True(t, isDate)
+
+	ref, err = RelativeFromString("archlinux:latest")
+	require.NoError(t, err)
+	require.Nil(t, ref.Reflog())
+
+	_, err = RelativeFromString("archlinux:latest@{1}")
+	require.Equal(t, ErrReflogSelectorRequiresHead, err)
+
+	_, err = RelativeFromString("archlinux@{someday}")
+	require.Equal(t, ErrInvalidReflogSelectorFormat, err)
+}